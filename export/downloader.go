@@ -0,0 +1,303 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"skool-video-dl/loom"
+	"skool-video-dl/media"
+)
+
+// -----------------------------------------------------------------------------
+// Downloader => backends de téléchargement des vidéos, choisis par hôte
+// -----------------------------------------------------------------------------
+
+// Downloader télécharge les vidéos des modules.
+type Downloader interface {
+	// Name est le nom du backend dans -downloader ("ytdlp", "native"...).
+	Name() string
+	// Resolve retourne les URL à essayer, dans l'ordre, pour le lien link ;
+	// une erreur si le backend ne sait pas le traiter.
+	Resolve(ctx context.Context, link string) ([]string, error)
+	// Download écrit la vidéo u dans path et retourne le fichier écrit (dont
+	// l'extension peut différer). progress peut être nil.
+	Download(ctx context.Context, u, path string, progress media.Progress) (string, error)
+}
+
+// Noms des backends intégrés.
+const (
+	BackendYTDLP   = "ytdlp"
+	BackendNative  = "native"
+	BackendCommand = "cmd"
+)
+
+// Native est le Downloader en Go : vidéos Loom (voir loom.Client), fichiers
+// directs, playlists HLS et manifests DASH (voir media.Download).
+type Native struct {
+	// HTTP télécharge les fichiers ; http.DefaultClient si nil.
+	HTTP *http.Client
+	// Loom résout les vidéos Loom ; un loom.Client sur HTTP si nil.
+	Loom *loom.Client
+}
+
+// Name retourne "native".
+func (Native) Name() string { return BackendNative }
+
+// Resolve retourne link s'il s'agit d'une vidéo Loom ou d'un média reconnu
+// par media.KindOf, media.ErrUnsupported sinon.
+func (Native) Resolve(_ context.Context, link string) ([]string, error) {
+	if loom.VideoID(link) == "" && media.KindOf(link) == media.Unknown {
+		return nil, fmt.Errorf("%w: %s", media.ErrUnsupported, link)
+	}
+	return []string{link}, nil
+}
+
+// Download télécharge u dans path (voir loom.Client.Download et
+// media.Download). Un fichier direct garde son extension (.webm, .mov...).
+func (n Native) Download(ctx context.Context, u, path string, progress media.Progress) (string, error) {
+	if ext := urlExt(u); media.KindOf(u) == media.File && ext != "" {
+		path = strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	}
+	if loom.VideoID(u) != "" {
+		client := n.Loom
+		if client == nil {
+			client = &loom.Client{HTTP: n.HTTP}
+		}
+		return client.Download(ctx, u, path, progress)
+	}
+	return media.Download(ctx, n.HTTP, u, path, progress)
+}
+
+// urlExt retourne l'extension, en minuscules, du chemin de l'URL u.
+func urlExt(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(path.Ext(p.Path))
+}
+
+// Command est le Downloader qui lance une commande shell (-download-cmd).
+// Dans Template, {url}, {output} (fichier attendu), {dir}, {name} (nom du
+// fichier sans extension) et {referer} (page de la leçon) sont remplacés par
+// des valeurs échappées pour sh.
+type Command struct {
+	Template string
+	Referer  string
+	// Out reçoit la sortie de la commande ; os.Stdout si nil.
+	Out io.Writer
+}
+
+// Name retourne "cmd".
+func (Command) Name() string { return BackendCommand }
+
+// Resolve retourne link.
+func (Command) Resolve(_ context.Context, link string) ([]string, error) {
+	return []string{link}, nil
+}
+
+// Download lance la commande puis retourne path, ou la vidéo <name>.* écrite
+// à sa place dans le même dossier (voir findVideo).
+func (c Command) Download(ctx context.Context, u, path string, _ media.Progress) (string, error) {
+	if c.Template == "" {
+		return "", errors.New("no download command (-download-cmd)")
+	}
+	dir := filepath.Dir(path)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	line := strings.NewReplacer(
+		"{url}", shellQuote(u),
+		"{output}", shellQuote(path),
+		"{dir}", shellQuote(dir),
+		"{name}", shellQuote(name),
+		"{referer}", shellQuote(c.Referer),
+	).Replace(c.Template)
+	out := c.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", line)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("download command: %w", err)
+	}
+	if fn := findVideo(path); fn != "" {
+		return fn, nil
+	}
+	return "", fmt.Errorf("download command wrote no %s", filepath.Base(path))
+}
+
+// shellQuote entoure s d'apostrophes pour sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// -----------------------------------------------------------------------------
+// Routes => backends par hôte
+// -----------------------------------------------------------------------------
+
+// DownloadRoute associe un hôte aux backends essayés, dans l'ordre, pour ses
+// vidéos. Host couvre aussi ses sous-domaines ; "*" s'applique aux autres.
+type DownloadRoute struct {
+	Host     string
+	Backends []string
+}
+
+// DefaultDownloadRoutes essaie le backend Go puis yt-dlp pour toutes les
+// vidéos.
+const DefaultDownloadRoutes = "*=native+ytdlp"
+
+// ParseDownloadRoutes lit une liste de routes séparées par des virgules :
+// "vimeo.com=ytdlp,loom.com=native,*=native+ytdlp".
+func ParseDownloadRoutes(s string) ([]DownloadRoute, error) {
+	var out []DownloadRoute
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		host, backends, ok := strings.Cut(r, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid download route %q (want host=backend+backend)", r)
+		}
+		route := DownloadRoute{Host: host}
+		for _, b := range strings.Split(backends, "+") {
+			b = strings.ToLower(strings.TrimSpace(b))
+			switch b {
+			case BackendYTDLP, BackendNative, BackendCommand:
+				route.Backends = append(route.Backends, b)
+			default:
+				return nil, fmt.Errorf("unknown downloader %q for %s (want %s, %s or %s)", b, host, BackendNative, BackendYTDLP, BackendCommand)
+			}
+		}
+		out = append(out, route)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no download route in %q", s)
+	}
+	return out, nil
+}
+
+// UsesBackend indique si une des routes passe par le backend name.
+func UsesBackend(routes []DownloadRoute, name string) bool {
+	for _, r := range routes {
+		if slices.Contains(r.Backends, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// backendsFor retourne les backends à essayer pour link : ceux de la route de
+// son hôte, sinon ceux de "*", sinon ceux de DefaultDownloadRoutes.
+func (e *Exporter) backendsFor(link string) []string {
+	routes := e.Downloaders
+	if len(routes) == 0 {
+		routes, _ = ParseDownloadRoutes(DefaultDownloadRoutes)
+	}
+	var host string
+	if u, err := url.Parse(link); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	var fallback []string
+	for _, r := range routes {
+		switch {
+		case r.Host == "*":
+			if fallback == nil {
+				fallback = r.Backends
+			}
+		case host == r.Host || strings.HasSuffix(host, "."+r.Host):
+			return r.Backends
+		}
+	}
+	if fallback == nil {
+		fallback = []string{BackendNative, BackendYTDLP}
+	}
+	return fallback
+}
+
+// backend retourne le Downloader nommé name : celui de Backends s'il y est,
+// sinon le backend intégré configuré par l'Exporter, referer étant la page
+// de la leçon qui intègre la vidéo et out la sortie des outils externes.
+func (e *Exporter) backend(name, referer string, out io.Writer) Downloader {
+	if d, ok := e.Backends[name]; ok {
+		return d
+	}
+	switch name {
+	case BackendYTDLP:
+		return YTDLP{Args: e.ytdlpArgs(), Referer: referer, Out: out}
+	case BackendNative:
+		return Native{HTTP: e.httpClient(), Loom: e.Loom}
+	case BackendCommand:
+		return Command{Template: e.DownloadCmd, Referer: referer, Out: out}
+	}
+	return nil
+}
+
+// lineWriter recopie ce qu'on lui écrit dans la sortie de l'Exporter, une
+// ligne complète à la fois et avec le préfixe du module : les sorties des
+// téléchargements parallèles ne s'entremêlent pas.
+type lineWriter struct {
+	ml   moduleLog
+	mu   sync.Mutex
+	part []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.part = append(w.part, p...)
+	for {
+		i := bytes.IndexAny(w.part, "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		if line := w.part[:i]; len(bytes.TrimSpace(line)) > 0 {
+			w.ml.printf("%s\n", line)
+		}
+		w.part = w.part[i+1:]
+	}
+}
+
+// flush écrit la dernière ligne, restée sans fin de ligne.
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(bytes.TrimSpace(w.part)) > 0 {
+		w.ml.printf("%s\n", w.part)
+	}
+	w.part = nil
+}
+
+// videoProgress affiche l'avancement de la vidéo idx par paliers de 10 %.
+func (e *Exporter) videoProgress(ml moduleLog, idx int) media.Progress {
+	var (
+		mu   sync.Mutex
+		last int64
+	)
+	return func(done, total int64) {
+		if total <= 0 {
+			return
+		}
+		step := min(done*10/total, 10) * 10
+		mu.Lock()
+		defer mu.Unlock()
+		if step > last {
+			last = step
+			ml.printf("video %02d: %d%%\n", idx, step)
+		}
+	}
+}
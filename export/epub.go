@@ -0,0 +1,362 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"skool-video-dl/tiptap"
+)
+
+// -----------------------------------------------------------------------------
+// EPUB 3 => un cours en un seul fichier, table des matières navigable
+// -----------------------------------------------------------------------------
+
+// epubChapter est une leçon du livre.
+type epubChapter struct {
+	file string
+	md   ModuleData
+	// xhtml est la page rendue (voir render) ; remote indique qu'elle intègre
+	// un contenu distant (iframe, image non téléchargée, vidéo...), à déclarer
+	// dans le manifest.
+	xhtml  string
+	remote bool
+}
+
+// reRemoteSrc repère un attribut src qui pointe hors du livre.
+var reRemoteSrc = regexp.MustCompile(`(?i)\ssrc="(?:https?:)?//`)
+
+// epubImage est une image copiée localement, embarquée dans le livre.
+type epubImage struct {
+	id, href, mediaType string
+	data                []byte
+}
+
+// BuildEPUB écrit dans path un EPUB 3 du cours c : une page par leçon
+// (description, images locales, liens vers les vidéos) et une table des
+// matières dans l'ordre de la classroom, sets compris. Il n'utilise que
+// CourseData, comme BuildHTMLIndex, et les libellés par défaut.
+func BuildEPUB(path string, c CourseData) error {
+	return DefaultTemplates().BuildEPUB(path, c)
+}
+
+// BuildEPUB est la fonction BuildEPUB avec les libellés de t (voir Label).
+func (t *Templates) BuildEPUB(path string, c CourseData) error {
+	b := &epubBook{course: c, labels: t, images: map[string]string{}}
+	b.collect(c.Modules)
+	for i := range b.chapters {
+		b.render(&b.chapters[i])
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	// mimetype doit être la première entrée, non compressée.
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"META-INF/container.xml": epubContainer,
+		"OEBPS/style.css":        epubCSS,
+		"OEBPS/nav.xhtml":        b.nav(),
+		"OEBPS/toc.ncx":          b.ncx(),
+		"OEBPS/content.opf":      b.opf(),
+	}
+	for _, ch := range b.chapters {
+		files["OEBPS/"+ch.file] = ch.xhtml
+	}
+	for _, name := range sortedKeys(files) {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, files[name]); err != nil {
+			return err
+		}
+	}
+	for _, im := range b.imageList {
+		w, err := zw.Create("OEBPS/" + im.href)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(im.data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+type epubBook struct {
+	course CourseData
+	// labels donne les titres et la langue du livre.
+	labels   *Templates
+	chapters []epubChapter
+	// images associe le fichier local d'une image à son chemin dans le livre.
+	images    map[string]string
+	imageList []epubImage
+}
+
+// collect numérote les leçons de mods (sets dépliés) et lit leurs images ;
+// une image illisible est ignorée (la page garde son URL d'origine).
+func (b *epubBook) collect(mods []ModuleData) {
+	for _, m := range mods {
+		if m.Set {
+			b.collect(m.Children)
+			continue
+		}
+		n := len(b.chapters) + 1
+		b.chapters = append(b.chapters, epubChapter{file: fmt.Sprintf("m%03d.xhtml", n), md: m})
+		for _, im := range m.Images {
+			if _, ok := b.images[im.Filename]; ok || !fileExistsAndNonZero(im.Filename) {
+				continue
+			}
+			data, err := os.ReadFile(im.Filename)
+			if err != nil {
+				continue
+			}
+			id := fmt.Sprintf("img%03d", len(b.imageList)+1)
+			href := "images/" + id + strings.ToLower(filepath.Ext(im.Filename))
+			b.images[im.Filename] = href
+			b.imageList = append(b.imageList, epubImage{id: id, href: href, mediaType: imageMediaType(im.Filename), data: data})
+		}
+	}
+}
+
+// render rend la page XHTML de la leçon ch dans ch.xhtml.
+func (b *epubBook) render(ch *epubChapter) {
+	md := ch.md
+	local := map[string]string{}
+	for _, im := range md.Images {
+		if href, ok := b.images[im.Filename]; ok {
+			local[im.URL] = href
+		}
+	}
+	r := tiptap.Renderer{
+		XHTML:       true,
+		Unsupported: b.labels.Label("unsupported"),
+		ImageSrc: func(src string) string {
+			if l, ok := local[src]; ok {
+				return l
+			}
+			return src
+		},
+	}
+	var sb strings.Builder
+	sb.WriteString(xhtmlHeader(b.labels.Label("lang"), md.Title))
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", xmlEscape(md.Title))
+	if desc := r.DescriptionHTML(md.RawDescription); desc != "" {
+		sb.WriteString(`<div class="content">` + desc + "</div>\n")
+	}
+	if len(md.Videos) > 0 {
+		fmt.Fprintf(&sb, "<h2>%s</h2>\n<ul>\n", xmlEscape(b.labels.Label("videos")))
+		for _, v := range md.Videos {
+			link := v.Source
+			if link == "" {
+				link = v.URL
+			}
+			fmt.Fprintf(&sb, `<li><a href="%s">%s</a></li>`+"\n", xmlEscape(link), xmlEscape(filepath.Base(v.Filename)))
+		}
+		sb.WriteString("</ul>\n")
+	}
+	if len(md.Resources) > 0 {
+		fmt.Fprintf(&sb, "<h2>%s</h2>\n<ul>\n", xmlEscape(b.labels.Label("resources")))
+		for _, res := range md.Resources {
+			fmt.Fprintf(&sb, `<li><a href="%s">%s</a></li>`+"\n", xmlEscape(res.URL), xmlEscape(res.Title))
+		}
+		sb.WriteString("</ul>\n")
+	}
+	sb.WriteString("</body>\n</html>\n")
+	ch.xhtml = sb.String()
+	ch.remote = reRemoteSrc.MatchString(ch.xhtml)
+}
+
+// nav rend la table des matières EPUB 3 ; un set devient un titre suivi de la
+// liste de ses leçons.
+func (b *epubBook) nav() string {
+	var sb strings.Builder
+	sb.WriteString(strings.Replace(xhtmlHeader(b.labels.Label("lang"), b.course.Title), "<html ", `<html xmlns:epub="http://www.idpf.org/2007/ops" `, 1))
+	sb.WriteString(`<nav epub:type="toc" id="toc">` + "\n")
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", xmlEscape(b.course.Title))
+	n := 0
+	var list func(mods []ModuleData)
+	list = func(mods []ModuleData) {
+		sb.WriteString("<ol>\n")
+		for _, m := range mods {
+			if m.Set {
+				fmt.Fprintf(&sb, "<li><span>%s</span>\n", xmlEscape(m.Title))
+				list(m.Children)
+				sb.WriteString("</li>\n")
+				continue
+			}
+			fmt.Fprintf(&sb, `<li><a href="%s">%s</a></li>`+"\n", b.chapters[n].file, xmlEscape(m.Title))
+			n++
+		}
+		sb.WriteString("</ol>\n")
+	}
+	list(b.course.Modules)
+	sb.WriteString("</nav>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+// ncx rend la table des matières EPUB 2, lue par les liseuses plus anciennes.
+// Un set pointe vers sa première leçon, avec le même ordre de lecture.
+func (b *epubBook) ncx() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+<head><meta name="dtb:uid" content="` + xmlEscape(b.identifier()) + `"/></head>
+`)
+	fmt.Fprintf(&sb, "<docTitle><text>%s</text></docTitle>\n<navMap>\n", xmlEscape(b.course.Title))
+	n, id := 0, 0
+	var points func(mods []ModuleData)
+	points = func(mods []ModuleData) {
+		for _, m := range mods {
+			id++
+			ch := min(n, len(b.chapters)-1)
+			fmt.Fprintf(&sb, `<navPoint id="np%d" playOrder="%d"><navLabel><text>%s</text></navLabel><content src="%s"/>`,
+				id, ch+1, xmlEscape(m.Title), b.chapters[ch].file)
+			if m.Set {
+				sb.WriteString("\n")
+				points(m.Children)
+			} else {
+				n++
+			}
+			sb.WriteString("</navPoint>\n")
+		}
+	}
+	if len(b.chapters) > 0 {
+		points(b.course.Modules)
+	}
+	sb.WriteString("</navMap>\n</ncx>\n")
+	return sb.String()
+}
+
+// opf rend le fichier package : métadonnées, manifest et ordre de lecture.
+func (b *epubBook) opf() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(&sb, "<dc:identifier id=\"uid\">%s</dc:identifier>\n", xmlEscape(b.identifier()))
+	fmt.Fprintf(&sb, "<dc:title>%s</dc:title>\n", xmlEscape(b.course.Title))
+	fmt.Fprintf(&sb, "<dc:language>%s</dc:language>\n", xmlEscape(b.labels.Label("lang")))
+	if b.course.URL != "" {
+		fmt.Fprintf(&sb, "<dc:source>%s</dc:source>\n", xmlEscape(b.course.URL))
+	}
+	fmt.Fprintf(&sb, "<meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	sb.WriteString(`</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="css" href="style.css" media-type="text/css"/>
+`)
+	for i, ch := range b.chapters {
+		props := ""
+		if ch.remote {
+			props = ` properties="remote-resources"`
+		}
+		fmt.Fprintf(&sb, `<item id="m%03d" href="%s" media-type="application/xhtml+xml"%s/>`+"\n", i+1, ch.file, props)
+	}
+	for _, im := range b.imageList {
+		fmt.Fprintf(&sb, `<item id="%s" href="%s" media-type="%s"/>`+"\n", im.id, im.href, im.mediaType)
+	}
+	sb.WriteString("</manifest>\n<spine toc=\"ncx\">\n<itemref idref=\"nav\"/>\n")
+	for i := range b.chapters {
+		fmt.Fprintf(&sb, "<itemref idref=\"m%03d\"/>\n", i+1)
+	}
+	sb.WriteString("</spine>\n</package>\n")
+	return sb.String()
+}
+
+// identifier est l'identifiant unique du livre : l'URL du cours.
+func (b *epubBook) identifier() string {
+	if b.course.URL != "" {
+		return b.course.URL
+	}
+	return "urn:skool:" + b.course.ID
+}
+
+func xhtmlHeader(lang, title string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="` + xmlEscape(lang) + `" lang="` + xmlEscape(lang) + `">
+<head>
+<meta charset="utf-8"/>
+<title>` + xmlEscape(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+`
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// imageMediaType retourne le type MIME de l'image path, d'après son
+// extension ou à défaut son contenu.
+func imageMediaType(path string) string {
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); strings.HasPrefix(t, "image/") {
+		return t
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+const epubCSS = `body { font-family: serif; line-height: 1.5; }
+h1, h2, h3 { font-family: sans-serif; }
+img { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 0.2em 0.4em; }
+blockquote { color: #555; border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; }
+.task-list { list-style: none; }
+.tiptap-unknown { border: 1px dashed #a00; color: #a00; }
+`
@@ -0,0 +1,505 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"skool-video-dl/loom"
+	"skool-video-dl/skool"
+	"skool-video-dl/tiptap"
+	"skool-video-dl/vimeo"
+)
+
+// Exporter exporte des cours Skool dans OutputDir.
+type Exporter struct {
+	Client    *skool.Client
+	OutputDir string
+	// Debug active les traces détaillées (liens trouvés, conversions).
+	Debug bool
+	// Out reçoit la progression ; os.Stdout si nil.
+	Out io.Writer
+	// CookiesFile est un cookies.txt (format Netscape) transmis à yt-dlp.
+	CookiesFile string
+	// HTTPClient télécharge les ressources des modules (voir
+	// skool.NewHTTPClient) ; http.DefaultClient si nil.
+	HTTPClient *http.Client
+	// Concurrency est le nombre de modules traités (et de pages lues) en
+	// parallèle (1 par défaut). Avec ChromeFetcher, prévoir autant d'onglets.
+	Concurrency int
+	// DownloadConcurrency est le nombre de téléchargements menés en parallèle
+	// (1 par défaut), tous modules confondus.
+	DownloadConcurrency int
+
+	// Formats liste les formats des pages de modules (FormatHTML,
+	// FormatMarkdown) ; FormatHTML seul si vide.
+	Formats []string
+	// Templates rend les pages HTML ; gabarits par défaut si nil.
+	Templates *Templates
+
+	// Loom télécharge les vidéos Loom sans yt-dlp ; un loom.Client sur
+	// HTTPClient si nil.
+	Loom *loom.Client
+	// Downloaders choisit les backends de téléchargement des vidéos selon
+	// l'hôte (voir ParseDownloadRoutes) ; DefaultDownloadRoutes si vide.
+	Downloaders []DownloadRoute
+	// DownloadCmd est le modèle de commande du backend "cmd" (voir Command).
+	DownloadCmd string
+	// Backends remplace des backends intégrés ou en ajoute, par nom.
+	Backends map[string]Downloader
+
+	// Filter restreint les modules traités ; nil les garde tous. Les modules
+	// écartés gardent leurs données du run précédent.
+	Filter *skool.Filter
+
+	// previous est le manifest du run précédent (voir LoadManifest).
+	previous *Manifest
+
+	report   SyncReport
+	reportMu sync.Mutex
+
+	// dirs associe chaque cours (URL) et module (ID) à son dossier, et
+	// dirOwners chaque dossier (voir dirKey) à son propriétaire.
+	dirs      map[string]string
+	dirOwners map[string]string
+	dirsMu    sync.Mutex
+
+	initOnce  sync.Once
+	pages     chan struct{}
+	downloads chan struct{}
+	outMu     sync.Mutex
+}
+
+// New retourne un Exporter qui écrit dans outputDir.
+func New(client *skool.Client, outputDir string) *Exporter {
+	return &Exporter{Client: client, OutputDir: outputDir}
+}
+
+// rel retourne path relatif à OutputDir (path inchangé si impossible).
+func (e *Exporter) rel(path string) string {
+	if r, err := filepath.Rel(e.OutputDir, path); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return path
+}
+
+func (e *Exporter) init() {
+	e.initOnce.Do(func() {
+		e.pages = make(chan struct{}, max(e.Concurrency, 1))
+		e.downloads = make(chan struct{}, max(e.DownloadConcurrency, 1))
+	})
+}
+
+func (e *Exporter) printf(format string, args ...interface{}) {
+	out := e.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	e.outMu.Lock()
+	defer e.outMu.Unlock()
+	fmt.Fprintf(out, format, args...)
+}
+
+// Formats d'export : pages de modules (html, md) ou livre par cours (epub).
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "md"
+	FormatEPUB     = "epub"
+)
+
+// ParseFormats lit une liste de formats séparés par des virgules ("html,md").
+func ParseFormats(s string) ([]string, error) {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch f {
+		case "":
+			continue
+		case FormatHTML, FormatMarkdown, FormatEPUB:
+			if !slices.Contains(out, f) {
+				out = append(out, f)
+			}
+		default:
+			return nil, fmt.Errorf("unknown format %q (want %s, %s or %s)", f, FormatHTML, FormatMarkdown, FormatEPUB)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no format in %q", s)
+	}
+	return out, nil
+}
+
+// HasFormat indique si l'export au format f est généré.
+func (e *Exporter) HasFormat(f string) bool {
+	if len(e.Formats) == 0 {
+		return f == FormatHTML
+	}
+	return slices.Contains(e.Formats, f)
+}
+
+// pageFiles retourne les noms des pages générées dans le dossier d'un module.
+func (e *Exporter) pageFiles() []string {
+	var out []string
+	if e.HasFormat(FormatHTML) {
+		out = append(out, "module.html")
+	}
+	if e.HasFormat(FormatMarkdown) {
+		out = append(out, "module.md")
+	}
+	return out
+}
+
+// parallel indique si plusieurs modules ou vidéos sont traités à la fois ;
+// la progression est alors préfixée par le module concerné.
+func (e *Exporter) parallel() bool {
+	return e.Concurrency > 1 || e.DownloadConcurrency > 1
+}
+
+// -----------------------------------------------------------------------------
+// ExportCourse => modules du cours, en parallèle borné
+// -----------------------------------------------------------------------------
+
+// ExportCourse exporte tous les modules du cours c. Les leçons sont traitées
+// en parallèle (Concurrency, DownloadConcurrency) mais CourseData.Modules
+// garde l'ordre et l'arborescence (sets) du cours. Les erreurs d'un module
+// sont affichées sans interrompre le cours.
+func (e *Exporter) ExportCourse(ctx context.Context, c skool.Course) (CourseData, error) {
+	e.init()
+	courseRel := e.courseRel(c)
+	courseDir := filepath.Join(e.OutputDir, courseRel)
+	if err := os.MkdirAll(courseDir, fs.ModePerm); err != nil {
+		return CourseData{}, err
+	}
+
+	mods, err := e.Client.Modules(ctx, c.URL)
+	if err != nil {
+		return CourseData{}, fmt.Errorf("cannot list modules: %w", err)
+	}
+	jobs := e.lessonJobs(mods, courseDir)
+	e.printf("  📚 Found %d module(s)\n", len(jobs))
+	e.recordRemoved(c.URL, c.Title, skool.Lessons(mods))
+
+	slots := make([]*ModuleData, len(jobs))
+	var kept []int
+	for j, job := range jobs {
+		if e.Filter.KeepModule(c, j, job.m) {
+			kept = append(kept, j)
+		} else if pm := e.previous.Module(job.m.ID); pm != nil {
+			prev := e.previousModule(moduleFromManifest(pm))
+			slots[j] = &prev
+		}
+	}
+	forEach(len(kept), e.Concurrency, func(k int) {
+		j, m := kept[k], jobs[kept[k]].m
+		e.printf("  [%d/%d] ➜ %s\n", j+1, len(jobs), m.Title)
+		ml := e.moduleLog(j, len(jobs))
+		modData, err := e.exportModule(ctx, c.Title, m, jobs[j].dir, ml)
+		if err != nil {
+			ml.printf("⚠️  %v\n", err)
+		}
+		slots[j] = &modData
+	})
+
+	done := map[string]ModuleData{}
+	for _, md := range slots {
+		if md != nil {
+			done[md.ID] = *md
+		}
+	}
+	cd := CourseData{ID: c.ID, Title: c.Title, URL: c.URL, Dir: courseRel}
+	cd.Modules = e.moduleTree(mods, courseDir, done)
+	e.writeCoursePages(cd)
+	return cd, nil
+}
+
+// forEach appelle fn(i) pour chaque i de 0 à n-1, sur un pool fixe de
+// workers goroutines (au moins une) qui se partagent les indices.
+func forEach(n, workers int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// writeCoursePages (ré)écrit les module.html du cours c : la navigation de
+// chaque page dépend de tout le cours, modules inchangés compris.
+func (e *Exporter) writeCoursePages(c CourseData) {
+	if !e.HasFormat(FormatHTML) {
+		return
+	}
+	if err := e.Templates.BuildCourseHTML(e.OutputDir, c); err != nil {
+		log.Printf("Cannot write module.html: %v\n", err)
+	}
+}
+
+// lessonJob est une leçon à exporter, le dossier (cours ou set) qui la
+// contient et les titres des sets englobants.
+type lessonJob struct {
+	m    skool.ModuleInfo
+	dir  string
+	sets []string
+}
+
+// lessonJobs déplie l'arbre mods en leçons, dans l'ordre du cours ; les sets
+// deviennent des sous-dossiers de dir.
+func (e *Exporter) lessonJobs(mods []skool.ModuleInfo, dir string, sets ...string) []lessonJob {
+	var jobs []lessonJob
+	for _, m := range mods {
+		if m.Set {
+			jobs = append(jobs, e.lessonJobs(m.Children, e.moduleDir(dir, m), append(slices.Clip(sets), m.Title)...)...)
+			continue
+		}
+		jobs = append(jobs, lessonJob{m: m, dir: dir, sets: sets})
+	}
+	return jobs
+}
+
+// moduleTree reconstruit l'arbre mods à partir des leçons exportées done
+// (par ID). Les leçons absentes et les sets restés vides sont omis.
+func (e *Exporter) moduleTree(mods []skool.ModuleInfo, dir string, done map[string]ModuleData) []ModuleData {
+	var out []ModuleData
+	for _, m := range mods {
+		if !m.Set {
+			if md, ok := done[m.ID]; ok {
+				out = append(out, md)
+			}
+			continue
+		}
+		setDir := e.moduleDir(dir, m)
+		children := e.moduleTree(m.Children, setDir, done)
+		if len(children) == 0 {
+			continue
+		}
+		out = append(out, ModuleData{
+			ID:        m.ID,
+			Title:     m.Title,
+			URL:       m.URL,
+			Dir:       e.rel(setDir),
+			UpdatedAt: m.UpdatedAt,
+			ParentID:  m.ParentID,
+			Set:       true,
+			Children:  children,
+		})
+	}
+	return out
+}
+
+// moduleLog affiche la progression d'un module. En séquentiel, l'en-tête
+// "[j/n] ➜ titre" précède les lignes du module ; en parallèle, chaque ligne
+// est préfixée par "[j/n]" pour rester lisible.
+type moduleLog struct {
+	e      *Exporter
+	prefix string
+}
+
+func (e *Exporter) moduleLog(j, n int) moduleLog {
+	if e.parallel() {
+		return moduleLog{e: e, prefix: fmt.Sprintf("  [%d/%d] ", j+1, n)}
+	}
+	return moduleLog{e: e, prefix: "    "}
+}
+
+func (l moduleLog) printf(format string, args ...interface{}) {
+	l.e.printf(l.prefix+format, args...)
+}
+
+func (l moduleLog) debugf(format string, args ...interface{}) {
+	if l.e.Debug {
+		l.printf(format, args...)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// ExportModule => parse Tiptap => vidéos, ressources, module.md
+// -----------------------------------------------------------------------------
+
+// ExportModule télécharge les vidéos et ressources de la leçon m dans un
+// sous-dossier de dir (dossier du cours, ou du set qui contient m). Un module
+// inchangé depuis le run précédent (même hash de contenu dans le manifest,
+// voir LoadManifest) n'est pas relu ; un module modifié est relu, seules les
+// nouvelles vidéos sont téléchargées. Les module.html dépendent de tout le
+// cours et ne sont pas écrits : appeler BuildCourseHTML avec le CourseData
+// complet (ExportCourse le fait).
+func (e *Exporter) ExportModule(ctx context.Context, m skool.ModuleInfo, dir string) (ModuleData, error) {
+	e.init()
+	return e.exportModule(ctx, filepath.Base(dir), m, dir, moduleLog{e: e, prefix: "    "})
+}
+
+func (e *Exporter) exportModule(ctx context.Context, course string, m skool.ModuleInfo, dir string, ml moduleLog) (ModuleData, error) {
+	modDir := e.moduleDir(dir, m)
+	md := ModuleData{ID: m.ID, Title: m.Title, URL: m.URL, Dir: e.rel(modDir), UpdatedAt: m.UpdatedAt, ContentHash: m.Hash, ParentID: m.ParentID}
+
+	st := e.moduleState(m, modDir)
+	e.record(st, course, m.Title)
+	switch st {
+	case moduleUnchanged:
+		ml.printf("unchanged since last run, skipping\n")
+		return e.previousModule(md), nil
+	case moduleChanged:
+		if pm := e.previous.Module(m.ID); pm != nil && pm.ContentHash == m.Hash {
+			ml.printf("incomplete last run, fetching missing files\n")
+		} else {
+			ml.printf("changed since last run, updating\n")
+		}
+	}
+
+	if err := os.MkdirAll(modDir, fs.ModePerm); err != nil {
+		return md, err
+	}
+
+	e.pages <- struct{}{}
+	lesson, err := e.Client.Lesson(ctx, m)
+	<-e.pages
+	if err != nil {
+		// On garde la version précédente (et son hash) : le module sera
+		// retenté au prochain run.
+		prev := e.previousModule(ModuleData{ID: m.ID, Title: m.Title, URL: m.URL, Dir: md.Dir, ParentID: m.ParentID})
+		return prev, fmt.Errorf("cannot read module %s: %w", m.Title, err)
+	}
+
+	md.ExportedAt = time.Now().UTC()
+	md.RawDescription = lesson.Description
+	md.Images = e.downloadImages(ctx, lesson.Description, modDir, ml)
+	md.Description = tiptap.Renderer{
+		ImageSrc: localImageSrc(md.Images),
+		Unknown: func(kind, typ string) {
+			ml.debugf("unsupported Tiptap %s %q\n", kind, typ)
+		},
+		Unsupported: e.Templates.Label("unsupported"),
+	}.DescriptionHTML(lesson.Description)
+	md.Videos, md.MissingVideos = e.downloadAll(ctx, e.videoLinks(lesson, ml), modDir, m, ml)
+	md.Resources = e.downloadResources(ctx, lesson.Resources, modDir, ml)
+
+	if e.HasFormat(FormatMarkdown) {
+		if err := e.Templates.BuildModuleMarkdown(filepath.Join(modDir, "module.md"), course, md); err != nil {
+			log.Printf("Cannot write module.md for %s: %v\n", m.Title, err)
+		}
+	}
+	return md, nil
+}
+
+// videoLinks fusionne les videoLink du module et les liens Loom/Vimeo de la
+// description. Les liens Vimeo sont réécrits en URL player.
+func (e *Exporter) videoLinks(l skool.Lesson, ml moduleLog) []string {
+	var allLinks []string
+	ml.debugf("videoLinks: %v\n", l.VideoLinks)
+	for _, link := range l.VideoLinks {
+		ml.debugf("processing video link: %s\n", link)
+		switch {
+		case !vimeo.IsVimeo(link):
+			// For non-Vimeo links (YouTube, Loom, etc.), add them as-is
+			ml.debugf("adding non-Vimeo URL as-is: %s\n", link)
+			allLinks = append(allLinks, link)
+		case vimeo.IsShareLink(link):
+			// For Vimeo URLs with /video/share?h=hash pattern, preserve the original URL
+			ml.debugf("preserving original Vimeo URL: %s\n", link)
+			allLinks = append(allLinks, link)
+		default:
+			converted := vimeo.ToPlayer(link)
+			ml.debugf("converted Vimeo URL: %s -> %s\n", link, converted)
+			allLinks = append(allLinks, converted)
+		}
+	}
+	allLinks = append(allLinks, tiptap.LoomVimeoLinks(l.Description)...)
+	return uniqueStrings(allLinks)
+}
+
+// ytdlpArgs retourne les options yt-dlp communes à tous les téléchargements.
+func (e *Exporter) ytdlpArgs() []string {
+	var args []string
+	if e.CookiesFile != "" {
+		args = append(args, "--cookies", e.CookiesFile)
+	}
+	if e.DownloadConcurrency > 1 {
+		// Plusieurs barres de progression entremêlées sont illisibles.
+		args = append(args, "--quiet")
+	}
+	return args
+}
+
+// downloadAll télécharge chaque lien de la leçon m dans modDir, sous le titre
+// de m (voir videoBase), jusqu'à DownloadConcurrency à la fois. Pour Vimeo,
+// toutes les variantes d'URL sont essayées jusqu'au premier succès. Les
+// VideoRecord suivent l'ordre de links ; missing liste les liens dont tous les
+// téléchargements ont échoué.
+func (e *Exporter) downloadAll(ctx context.Context, links []string, modDir string, m skool.ModuleInfo, ml moduleLog) (recs []VideoRecord, missing []string) {
+	slots := make([]*VideoRecord, len(links))
+	forEach(len(links), e.DownloadConcurrency, func(i int) {
+		e.downloads <- struct{}{}
+		defer func() { <-e.downloads }()
+		slots[i] = e.downloadOne(ctx, links[i], modDir, m, i+1, ml)
+	})
+
+	for i, r := range slots {
+		if r == nil {
+			missing = append(missing, links[i])
+			continue
+		}
+		recs = append(recs, *r)
+	}
+	return recs, missing
+}
+
+// downloadOne télécharge link sous le numéro idx ; nil si tout a échoué.
+// Les backends de la route de link (voir backendsFor) sont essayés dans
+// l'ordre, chacun sur toutes les URL qu'il résout, jusqu'au premier succès.
+// La page de la leçon m sert de Referer (lecteurs Vimeo privés).
+func (e *Exporter) downloadOne(ctx context.Context, link, modDir string, m skool.ModuleInfo, idx int, ml moduleLog) *VideoRecord {
+	fn := e.previousVideo(m.ID, link)
+	if fn == "" {
+		fn = existingVideo(modDir, m.Title, idx)
+	}
+	if fn != "" {
+		ml.printf("skipping existing file %s\n", filepath.Base(fn))
+		return &VideoRecord{URL: link, Source: link, Filename: fn}
+	}
+	// Sortie de yt-dlp / -download-cmd, ligne à ligne dans Out.
+	out := &lineWriter{ml: ml}
+	defer out.flush()
+	for _, name := range e.backendsFor(link) {
+		d := e.backend(name, m.URL, out)
+		if d == nil {
+			ml.printf("  ⚠️  unknown downloader %q\n", name)
+			continue
+		}
+		urls, err := d.Resolve(ctx, link)
+		if err != nil {
+			ml.debugf("%s: %v\n", name, err)
+			continue
+		}
+		for _, u := range urls {
+			ml.printf("downloading (%s) => %s\n", name, u)
+			fn, err := d.Download(ctx, u, videoFile(modDir, m.Title, idx), e.videoProgress(ml, idx))
+			out.flush()
+			if err != nil {
+				ml.printf("  ⚠️  fail dl (%s): %v\n", name, err)
+				continue
+			}
+			return &VideoRecord{URL: u, Source: link, Filename: fn}
+		}
+	}
+	// Continue processing other videos even if this one fails
+	ml.printf("  ⚠️  all download attempts failed for: %s\n", link)
+	return nil
+}
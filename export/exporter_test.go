@@ -0,0 +1,43 @@
+package export
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEach(t *testing.T) {
+	for _, tt := range []struct{ n, workers, wantMax int }{
+		{0, 3, 0},
+		{5, 0, 1},
+		{5, 1, 1},
+		{10, 3, 3},
+		{2, 8, 2},
+	} {
+		var running, peak atomic.Int32
+		var mu sync.Mutex
+		seen := map[int]int{}
+		forEach(tt.n, tt.workers, func(i int) {
+			if r := running.Add(1); r > peak.Load() {
+				peak.Store(r)
+			}
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			seen[i]++
+			mu.Unlock()
+			running.Add(-1)
+		})
+		if len(seen) != tt.n {
+			t.Errorf("forEach(%d, %d) called %d indices", tt.n, tt.workers, len(seen))
+		}
+		for i, c := range seen {
+			if c != 1 {
+				t.Errorf("forEach(%d, %d) called %d %d times", tt.n, tt.workers, i, c)
+			}
+		}
+		if int(peak.Load()) > tt.wantMax {
+			t.Errorf("forEach(%d, %d) ran %d at once, want at most %d", tt.n, tt.workers, peak.Load(), tt.wantMax)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"os"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Helpers
+// -----------------------------------------------------------------------------
+// Clean transforme un titre en nom de fichier sûr : accents retirés,
+// caractères spéciaux remplacés par "-", espaces normalisés.
+func Clean(input string) string {
+	s := strings.TrimSpace(input)
+	s = removeAccents(s)
+	bad := []string{"/", "\\", ":", "?", "*", "\"", "<", ">", "|", "(", ")", "’", "'", "“", "”", "‘", "«", "»", "…", "!", "#", "&", "=", "+", "$"}
+	for _, c := range bad {
+		s = strings.ReplaceAll(s, c, "-")
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	return s
+}
+
+func removeAccents(input string) string {
+	var sb strings.Builder
+	for _, r := range input {
+		if r > 127 {
+			switch r {
+			case 'é', 'è', 'ê', 'ë':
+				r = 'e'
+			case 'à', 'â':
+				r = 'a'
+			case 'ô':
+				r = 'o'
+			case 'ù', 'û':
+				r = 'u'
+			case 'î', 'ï':
+				r = 'i'
+			default:
+				r = ' '
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func uniqueStrings(arr []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range arr {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func fileExistsAndNonZero(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Size() > 0
+}
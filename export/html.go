@@ -0,0 +1,329 @@
+package export
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+// Templates => pages HTML rendues par html/template, surchargeables
+// -----------------------------------------------------------------------------
+
+//go:embed templates
+var defaultFS embed.FS
+
+// Templates rend les pages HTML de l'export : module.html (page d'un module),
+// index.html (index global) et les gabarits qu'ils incluent (style.css,
+// portal.js, nav.html). labels.html définit tous les textes affichés : ceux
+// des gabarits ({{template "label.<nom>"}}) et ceux écrits par le code pour
+// les pages HTML, Markdown et EPUB (voir Label).
+type Templates struct {
+	set *template.Template
+}
+
+var (
+	defaultOnce sync.Once
+	defaultTpl  *Templates
+)
+
+// DefaultTemplates retourne les gabarits embarqués dans le binaire.
+func DefaultTemplates() *Templates {
+	defaultOnce.Do(func() {
+		defaultTpl = &Templates{set: template.Must(template.ParseFS(defaultFS, "templates/*"))}
+	})
+	return defaultTpl
+}
+
+// LoadTemplates retourne les gabarits par défaut, remplacés par ceux de dir
+// de même nom (module.html, index.html, style.css, portal.js, nav.html,
+// labels.html). Les autres fichiers de dir sont chargés aussi : ils peuvent
+// définir des gabarits partagés ({{define "..."}}). dir vide => gabarits par
+// défaut.
+func LoadTemplates(dir string) (*Templates, error) {
+	if dir == "" {
+		return DefaultTemplates(), nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	set, err := template.ParseFS(defaultFS, "templates/*")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	if len(files) > 0 {
+		if set, err = set.ParseFiles(files...); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range []string{"module.html", "index.html"} {
+		if set.Lookup(name) == nil {
+			return nil, fmt.Errorf("template %s is missing", name)
+		}
+	}
+	return &Templates{set: set}, nil
+}
+
+// get retourne t, ou les gabarits par défaut si t est nil.
+func (t *Templates) get() *Templates {
+	if t == nil {
+		return DefaultTemplates()
+	}
+	return t
+}
+
+// write rend le gabarit name dans path. La page n'est écrite qu'une fois
+// entièrement rendue.
+func (t *Templates) write(path, name string, data any) error {
+	var buf bytes.Buffer
+	if err := t.get().set.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Label retourne le libellé name défini par le gabarit "label.<name>" de
+// labels.html (ex. "home" => "Accueil"), en texte brut ; "" s'il n'existe
+// pas.
+func (t *Templates) Label(name string) string {
+	var buf bytes.Buffer
+	if err := t.get().set.ExecuteTemplate(&buf, "label."+name, nil); err != nil {
+		return ""
+	}
+	return html.UnescapeString(strings.TrimSpace(buf.String()))
+}
+
+// -----------------------------------------------------------------------------
+// BuildCourseHTML => module.html de chaque leçon, avec la navigation du cours
+// -----------------------------------------------------------------------------
+
+// ModulePage est la donnée passée au gabarit module.html.
+type ModulePage struct {
+	// Course est le titre du cours.
+	Course string
+	Module ModuleData
+	// Content est la description rendue (Module.Description), déjà sûre.
+	Content   template.HTML
+	Videos    []PageVideo
+	Resources []PageResource
+
+	// Root est le chemin relatif de la page vers la racine de l'export
+	// ("../../"), où se trouvent index.html et search-index.js.
+	Root string
+	// Breadcrumbs va de l'index au set qui contient la leçon ; un set n'a pas
+	// de Href.
+	Breadcrumbs []PageLink
+	// Prev et Next sont les leçons voisines dans l'ordre du cours.
+	Prev, Next *PageLink
+	// Nav est l'arbre du cours pour la barre latérale (liens relatifs à la
+	// page, leçon courante marquée).
+	Nav []IndexModule
+	// LessonIDs liste les leçons du cours, pour sa progression.
+	LessonIDs []string
+}
+
+// PageLink est un lien de navigation.
+type PageLink struct {
+	Title, Href string
+}
+
+// PageVideo est une vidéo téléchargée, vue depuis le dossier du module.
+type PageVideo struct {
+	// Name est le nom du fichier, Src le lien relatif vers celui-ci et Type
+	// son type MIME ("" s'il n'est pas reconnu).
+	Name, Src, Type string
+	// URL est l'URL téléchargée, Source le lien trouvé dans le module.
+	URL, Source string
+}
+
+// PageResource est une ressource du module : fichier local si Local, sinon
+// lien en ligne.
+type PageResource struct {
+	Title, Href string
+	Local       bool
+}
+
+// BuildCourseHTML écrit les pages du cours c avec les gabarits par défaut.
+func BuildCourseHTML(outDir string, c CourseData) error {
+	return DefaultTemplates().BuildCourseHTML(outDir, c)
+}
+
+// BuildCourseHTML écrit le module.html de chaque leçon du cours c (dossiers
+// relatifs à outDir) : barre latérale du cours, fil d'Ariane, leçons
+// précédente / suivante, description, vidéos et ressources.
+func (t *Templates) BuildCourseHTML(outDir string, c CourseData) error {
+	lessons := courseLessons(c.Modules, nil)
+	ids := make([]string, len(lessons))
+	for i, l := range lessons {
+		ids[i] = l.md.ID
+	}
+	var errs []error
+	for i, l := range lessons {
+		md := l.md
+		if md.Dir == "" {
+			continue
+		}
+		root := strings.Repeat("../", strings.Count(md.Dir, "/")+1)
+		page := ModulePage{
+			Course:    c.Title,
+			Module:    md,
+			Content:   template.HTML(md.Description),
+			Root:      root,
+			Nav:       indexModules(root, c.Modules, md.ID),
+			LessonIDs: ids,
+			Breadcrumbs: []PageLink{
+				{Title: t.Label("home"), Href: root + "index.html"},
+				{Title: c.Title, Href: root + "index.html#" + courseAnchor(c)},
+			},
+		}
+		for _, s := range l.sets {
+			page.Breadcrumbs = append(page.Breadcrumbs, PageLink{Title: s})
+		}
+		if i > 0 {
+			page.Prev = &PageLink{Title: lessons[i-1].md.Title, Href: root + lessonHref(lessons[i-1].md)}
+		}
+		if i+1 < len(lessons) {
+			page.Next = &PageLink{Title: lessons[i+1].md.Title, Href: root + lessonHref(lessons[i+1].md)}
+		}
+		for _, v := range md.Videos {
+			base := filepath.Base(v.Filename)
+			page.Videos = append(page.Videos, PageVideo{Name: base, Src: url.PathEscape(base), Type: videoMIME(base), URL: v.URL, Source: v.Source})
+		}
+		for _, r := range md.Resources {
+			if r.Filename != "" {
+				page.Resources = append(page.Resources, PageResource{Title: r.Title, Href: url.PathEscape(filepath.Base(r.Filename)), Local: true})
+			} else {
+				page.Resources = append(page.Resources, PageResource{Title: r.Title, Href: r.URL})
+			}
+		}
+		fp := filepath.Join(outDir, filepath.FromSlash(md.Dir), "module.html")
+		if err := t.write(fp, "module.html", page); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", md.Title, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// courseLesson est une leçon du cours et les titres des sets qui la
+// contiennent.
+type courseLesson struct {
+	md   ModuleData
+	sets []string
+}
+
+// courseLessons déplie l'arbre mods en leçons, dans l'ordre du cours.
+func courseLessons(mods []ModuleData, sets []string) []courseLesson {
+	var out []courseLesson
+	for _, m := range mods {
+		if m.Set {
+			out = append(out, courseLessons(m.Children, append(slices.Clip(sets), m.Title))...)
+			continue
+		}
+		out = append(out, courseLesson{md: m, sets: sets})
+	}
+	return out
+}
+
+// lessonHref retourne le lien vers la page de md depuis la racine de
+// l'export.
+func lessonHref(md ModuleData) string {
+	return path.Join(md.Dir, "module.html")
+}
+
+// courseAnchor retourne l'ancre du cours c dans index.html.
+func courseAnchor(c CourseData) string {
+	if c.ID != "" {
+		return "course-" + c.ID
+	}
+	return "course-" + Clean(c.Title)
+}
+
+// -----------------------------------------------------------------------------
+// BuildHTMLIndex => index global + index de recherche
+// -----------------------------------------------------------------------------
+
+// IndexPage est la donnée passée au gabarit index.html.
+type IndexPage struct {
+	Title   string
+	Courses []IndexCourse
+	// Root vaut "" : l'index est à la racine de l'export (voir ModulePage).
+	Root string
+}
+
+// IndexCourse est un cours de l'index ; Modules suit l'arbre des sets.
+type IndexCourse struct {
+	Course CourseData
+	// Anchor est l'id de la section du cours, cible du fil d'Ariane.
+	Anchor    string
+	Modules   []IndexModule
+	LessonIDs []string
+}
+
+// IndexModule est un module de la navigation. Href est le lien vers sa page ;
+// un set n'a que des Children. Current marque la leçon de la page affichée.
+type IndexModule struct {
+	Module   ModuleData
+	Href     string
+	Current  bool
+	Children []IndexModule
+}
+
+// BuildHTMLIndex écrit outDir/index.html avec les gabarits par défaut.
+func BuildHTMLIndex(all []CourseData, outDir string) error {
+	return DefaultTemplates().BuildHTMLIndex(all, outDir)
+}
+
+// BuildHTMLIndex écrit outDir/index.html, avec un lien vers chaque module
+// regroupés par set et la progression de chaque cours, et l'index de
+// recherche outDir/search-index.js.
+func (t *Templates) BuildHTMLIndex(all []CourseData, outDir string) error {
+	page := IndexPage{Title: t.Label("title")}
+	for _, c := range all {
+		ic := IndexCourse{Course: c, Anchor: courseAnchor(c), Modules: indexModules("", c.Modules, "")}
+		for _, l := range courseLessons(c.Modules, nil) {
+			ic.LessonIDs = append(ic.LessonIDs, l.md.ID)
+		}
+		page.Courses = append(page.Courses, ic)
+	}
+	if err := writeSearchIndex(filepath.Join(outDir, searchIndexName), all); err != nil {
+		return err
+	}
+	return t.write(filepath.Join(outDir, "index.html"), "index.html", page)
+}
+
+// indexModules prépare les modules mods pour la navigation : les liens sont
+// préfixés par root, la leçon current est marquée. Une leçon sans Dir (jamais
+// exportée) n'a pas de lien.
+func indexModules(root string, mods []ModuleData, current string) []IndexModule {
+	var out []IndexModule
+	for _, m := range mods {
+		im := IndexModule{Module: m}
+		if m.Set {
+			im.Children = indexModules(root, m.Children, current)
+		} else {
+			if m.Dir != "" {
+				im.Href = root + lessonHref(m)
+			}
+			im.Current = current != "" && m.ID == current
+		}
+		out = append(out, im)
+	}
+	return out
+}
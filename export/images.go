@@ -0,0 +1,93 @@
+package export
+
+import (
+	"context"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"skool-video-dl/tiptap"
+)
+
+// -----------------------------------------------------------------------------
+// Images => copie locale dans <module>/assets, src réécrits
+// -----------------------------------------------------------------------------
+
+// assetsDir est le sous-dossier d'un module qui reçoit ses images.
+const assetsDir = "assets"
+
+// downloadImages télécharge les images http(s) de la description desc dans
+// modDir/assets. Les ImageRecord suivent l'ordre de la description ; une image
+// en échec n'y figure pas et garde son URL d'origine.
+func (e *Exporter) downloadImages(ctx context.Context, desc, modDir string, ml moduleLog) []ImageRecord {
+	var srcs []string
+	for _, src := range tiptap.ImageURLs(desc) {
+		if isHTTPURL(src) {
+			srcs = append(srcs, src)
+		}
+	}
+	if len(srcs) == 0 {
+		return nil
+	}
+	dir := filepath.Join(modDir, assetsDir)
+	used := map[string]bool{}
+	var recs []ImageRecord
+	for _, src := range srcs {
+		name := uniqueFileName(imageFileName(src), "image", used)
+		ml.debugf("downloading image => %s\n", name)
+		e.downloads <- struct{}{}
+		fn, err := e.downloadImage(ctx, src, dir, name)
+		<-e.downloads
+		if err != nil {
+			ml.printf("  ⚠️  fail dl image %s: %v\n", src, err)
+			continue
+		}
+		recs = append(recs, ImageRecord{URL: src, Filename: fn})
+	}
+	return recs
+}
+
+func (e *Exporter) downloadImage(ctx context.Context, src, dir, name string) (string, error) {
+	if err := os.MkdirAll(dir, fs.ModePerm); err != nil {
+		return "", err
+	}
+	return DownloadFile(ctx, e.httpClient(), src, dir, name)
+}
+
+// isHTTPURL indique si src est une URL http(s) ; les images data: sont déjà
+// hors ligne.
+func isHTTPURL(src string) bool {
+	u, err := url.Parse(src)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// imageFileName retourne le nom de fichier de l'URL src ("" si aucun, ou si
+// le chemin finit par "/." ou "/..").
+func imageFileName(src string) string {
+	u, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	base := path.Base(u.Path)
+	if base == "/" || base == "." || base == ".." {
+		return ""
+	}
+	return base
+}
+
+// localImageSrc retourne la réécriture des src de la description vers les
+// copies locales images, en chemins relatifs au dossier du module.
+func localImageSrc(images []ImageRecord) tiptap.ImageSrcFunc {
+	local := map[string]string{}
+	for _, im := range images {
+		local[im.URL] = assetsDir + "/" + url.PathEscape(filepath.Base(im.Filename))
+	}
+	return func(src string) string {
+		if l, ok := local[src]; ok {
+			return l
+		}
+		return src
+	}
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"skool-video-dl/skool"
+)
+
+// -----------------------------------------------------------------------------
+// Layout => dossiers numérotés, uniques et stables d'un run à l'autre
+// -----------------------------------------------------------------------------
+
+// dirName retourne le nom du dossier d'un élément en position index (1-based)
+// de son parent : "03 - Titre". Sans position connue (0), le titre seul. Un
+// titre vide une fois nettoyé (ex. non latin) ou fait de points est remplacé
+// par fallback.
+func dirName(index int, title, fallback string) string {
+	name := Clean(title)
+	if emptyName(name) {
+		name = fallback
+	}
+	if index <= 0 {
+		return name
+	}
+	return fmt.Sprintf("%02d - %s", index, name)
+}
+
+// emptyName indique si name ne peut pas servir de nom de fichier : vide, "."
+// ou "..".
+func emptyName(name string) bool {
+	return strings.Trim(name, ".") == ""
+}
+
+// claimDir attribue à owner (URL d'un cours ou ID d'un module) un dossier
+// sous parent ("." pour la racine), relatif à OutputDir et séparé par "/".
+//
+// Le dossier du run précédent est repris tel quel tant que l'élément reste
+// sous le même parent : un titre ou une position modifiés sur Skool
+// n'orphelinent pas les fichiers déjà téléchargés. Sinon c'est parent/name,
+// suffixé " (2)", " (3)"… si ce dossier appartient déjà à un autre élément,
+// dans ce run ou le précédent. Un même owner obtient toujours le même dossier.
+func (e *Exporter) claimDir(owner, parent, name string) string {
+	e.dirsMu.Lock()
+	defer e.dirsMu.Unlock()
+	if e.dirs == nil {
+		e.dirs = map[string]string{}
+		e.dirOwners = map[string]string{}
+	}
+	if d, ok := e.dirs[owner]; ok {
+		return d
+	}
+
+	d := e.previous.dir(owner)
+	if emptyName(path.Base(d)) || path.Dir(d) != path.Clean(parent) || !e.dirFree(d, owner) {
+		base := path.Join(parent, name)
+		d = base
+		for i := 2; !e.dirFree(d, owner); i++ {
+			d = fmt.Sprintf("%s (%d)", base, i)
+		}
+	}
+	e.dirs[owner] = d
+	e.dirOwners[dirKey(d)] = owner
+	return d
+}
+
+// dirFree indique si d n'appartient à aucun autre élément que owner.
+func (e *Exporter) dirFree(d, owner string) bool {
+	if o, ok := e.dirOwners[dirKey(d)]; ok && o != owner {
+		return false
+	}
+	if o := e.previous.dirOwner(d); o != "" && o != owner {
+		return false
+	}
+	return true
+}
+
+// dirKey normalise un dossier pour comparer les noms sans tenir compte de la
+// casse (systèmes de fichiers macOS / Windows).
+func dirKey(d string) string {
+	return strings.ToLower(path.Clean(d))
+}
+
+// courseRel retourne le dossier du cours c, relatif à OutputDir ; "course-<id>"
+// si son titre ne donne aucun nom.
+func (e *Exporter) courseRel(c skool.Course) string {
+	id := c.ID
+	if id == "" {
+		id = skool.CourseSlug(c.URL)
+	}
+	return e.claimDir(c.URL, ".", dirName(c.Index, c.Title, Clean("course-"+id)))
+}
+
+// moduleDir retourne le dossier du module m (leçon ou set) dans parentDir,
+// dossier du cours ou du set parent ; "module-<id>" si son titre ne donne
+// aucun nom.
+func (e *Exporter) moduleDir(parentDir string, m skool.ModuleInfo) string {
+	rel := e.claimDir(m.ID, e.rel(parentDir), dirName(m.Index, m.Title, Clean("module-"+m.ID)))
+	return filepath.Join(e.OutputDir, filepath.FromSlash(rel))
+}
@@ -0,0 +1,417 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+// manifest.json => description machine de tout l'export
+// -----------------------------------------------------------------------------
+
+const (
+	// ManifestName est le nom du manifest, à la racine du dossier d'export.
+	ManifestName = "manifest.json"
+	// ManifestVersion est la version du format ; elle change à chaque
+	// modification incompatible. v2 : les sets figurent parmi les modules.
+	ManifestVersion = 2
+)
+
+// Manifest décrit un export : cours, modules, descriptions, vidéos et
+// ressources téléchargées. Les chemins sont relatifs au dossier d'export,
+// séparés par "/".
+type Manifest struct {
+	Version     int              `json:"version"`
+	GeneratedAt time.Time        `json:"generatedAt"`
+	SourceURL   string           `json:"sourceUrl,omitempty"`
+	Courses     []ManifestCourse `json:"courses"`
+
+	courses map[string]*ManifestCourse
+	modules map[string]*ManifestModule
+	files   map[string]*ManifestFile
+	// dirOwners associe chaque dossier (voir dirKey) au cours (URL) ou au
+	// module (ID) qui l'occupe.
+	dirOwners map[string]string
+}
+
+// ManifestCourse est un cours du manifest. Modules est l'arbre du cours mis à
+// plat : chaque set précède ses modules, qui le référencent par ParentID.
+type ManifestCourse struct {
+	ID      string           `json:"id,omitempty"`
+	Title   string           `json:"title"`
+	URL     string           `json:"url"`
+	Dir     string           `json:"dir"`
+	Modules []ManifestModule `json:"modules"`
+}
+
+// ManifestModule est un module du manifest : une leçon, ou un set (Set) sans
+// contenu propre.
+type ManifestModule struct {
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	URL             string         `json:"url"`
+	Dir             string         `json:"dir"`
+	ParentID        string         `json:"parentId,omitempty"`
+	Set             bool           `json:"set,omitempty"`
+	HTMLFile        string         `json:"htmlFile,omitempty"`
+	MarkdownFile    string         `json:"markdownFile,omitempty"`
+	DescriptionRaw  string         `json:"descriptionRaw,omitempty"`
+	DescriptionHTML string         `json:"descriptionHtml,omitempty"`
+	Videos          []ManifestFile `json:"videos,omitempty"`
+	// MissingVideos sont les liens vidéo non téléchargés (voir
+	// ModuleData.MissingVideos).
+	MissingVideos []string           `json:"missingVideos,omitempty"`
+	Resources     []ManifestResource `json:"resources,omitempty"`
+	// Images sont les images de la description copiées localement ;
+	// DescriptionHTML y fait référence par des chemins relatifs à Dir.
+	Images      []ManifestFile `json:"images,omitempty"`
+	ExportedAt  time.Time      `json:"exportedAt,omitzero"`
+	UpdatedAt   string         `json:"updatedAt,omitempty"`
+	ContentHash string         `json:"contentHash,omitempty"`
+}
+
+// ManifestFile est un fichier téléchargé.
+type ManifestFile struct {
+	// SourceURL est le lien trouvé sur Skool, DownloadURL celui qui a
+	// effectivement servi au téléchargement.
+	SourceURL   string    `json:"sourceUrl"`
+	DownloadURL string    `json:"downloadUrl,omitempty"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256,omitempty"`
+	ModifiedAt  time.Time `json:"modifiedAt,omitzero"`
+}
+
+// ManifestResource est une ressource jointe à un module : fichier téléchargé
+// (File), ou simple lien si File est nil.
+type ManifestResource struct {
+	Title string        `json:"title"`
+	URL   string        `json:"url"`
+	File  *ManifestFile `json:"file,omitempty"`
+}
+
+// LoadManifest lit outDir/manifest.json. Il retourne (nil, nil) si le fichier
+// n'existe pas.
+func LoadManifest(outDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(outDir, ManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestName, err)
+	}
+	if m.Version > ManifestVersion {
+		return nil, fmt.Errorf("%s: version %d is newer than supported version %d", ManifestName, m.Version, ManifestVersion)
+	}
+	m.index()
+	return &m, nil
+}
+
+func (m *Manifest) index() {
+	m.courses = map[string]*ManifestCourse{}
+	m.modules = map[string]*ManifestModule{}
+	m.files = map[string]*ManifestFile{}
+	m.dirOwners = map[string]string{}
+	for ci := range m.Courses {
+		m.courses[m.Courses[ci].URL] = &m.Courses[ci]
+		if m.Courses[ci].Dir != "" {
+			m.dirOwners[dirKey(m.Courses[ci].Dir)] = m.Courses[ci].URL
+		}
+		for mi := range m.Courses[ci].Modules {
+			mod := &m.Courses[ci].Modules[mi]
+			m.modules[mod.ID] = mod
+			if mod.Dir != "" {
+				m.dirOwners[dirKey(mod.Dir)] = mod.ID
+			}
+			for vi := range mod.Videos {
+				m.files[mod.Videos[vi].Path] = &mod.Videos[vi]
+			}
+			for _, r := range mod.Resources {
+				if r.File != nil {
+					m.files[r.File.Path] = r.File
+				}
+			}
+			for ii := range mod.Images {
+				m.files[mod.Images[ii].Path] = &mod.Images[ii]
+			}
+		}
+	}
+}
+
+// Course retourne le cours d'URL courseURL, nil s'il n'y figure pas.
+func (m *Manifest) Course(courseURL string) *ManifestCourse {
+	if m == nil {
+		return nil
+	}
+	return m.courses[courseURL]
+}
+
+// Module retourne le module id du manifest, nil s'il n'y figure pas.
+func (m *Manifest) Module(id string) *ManifestModule {
+	if m == nil {
+		return nil
+	}
+	return m.modules[id]
+}
+
+// dir retourne le dossier du cours (URL) ou du module (ID) owner, "" s'il n'y
+// figure pas.
+func (m *Manifest) dir(owner string) string {
+	if c := m.Course(owner); c != nil {
+		return c.Dir
+	}
+	if mod := m.Module(owner); mod != nil {
+		return mod.Dir
+	}
+	return ""
+}
+
+// dirOwner retourne le cours (URL) ou le module (ID) qui occupait le dossier
+// d, "" si aucun.
+func (m *Manifest) dirOwner(d string) string {
+	if m == nil {
+		return ""
+	}
+	return m.dirOwners[dirKey(d)]
+}
+
+// Save écrit le manifest dans outDir/manifest.json (via un fichier
+// temporaire, pour ne jamais laisser un manifest tronqué).
+func (m *Manifest) Save(outDir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(outDir, ManifestName+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(outDir, ManifestName))
+}
+
+// -----------------------------------------------------------------------------
+// Exporter <=> Manifest
+// -----------------------------------------------------------------------------
+
+// LoadManifest charge le manifest d'un run précédent dans OutputDir. Les
+// modules déjà exportés reprennent ainsi leurs données au lieu d'en perdre la
+// trace, et les checksums de fichiers inchangés ne sont pas recalculés.
+func (e *Exporter) LoadManifest() error {
+	prev, err := LoadManifest(e.OutputDir)
+	if err != nil {
+		return err
+	}
+	e.previous = prev
+	return nil
+}
+
+// previousModule complète md avec les données du run précédent.
+func (e *Exporter) previousModule(md ModuleData) ModuleData {
+	pm := e.previous.Module(md.ID)
+	if pm == nil {
+		return md
+	}
+	md.RawDescription = pm.DescriptionRaw
+	md.Description = pm.DescriptionHTML
+	md.ExportedAt = pm.ExportedAt
+	md.UpdatedAt = pm.UpdatedAt
+	md.ContentHash = pm.ContentHash
+	md.MissingVideos = pm.MissingVideos
+	for _, v := range pm.Videos {
+		md.Videos = append(md.Videos, VideoRecord{
+			URL:      v.DownloadURL,
+			Source:   v.SourceURL,
+			Filename: filepath.Join(e.OutputDir, filepath.FromSlash(v.Path)),
+		})
+	}
+	for _, r := range pm.Resources {
+		rec := ResourceRecord{Title: r.Title, URL: r.URL}
+		if r.File != nil {
+			rec.Filename = filepath.Join(e.OutputDir, filepath.FromSlash(r.File.Path))
+		}
+		md.Resources = append(md.Resources, rec)
+	}
+	for _, im := range pm.Images {
+		md.Images = append(md.Images, ImageRecord{
+			URL:      im.SourceURL,
+			Filename: filepath.Join(e.OutputDir, filepath.FromSlash(im.Path)),
+		})
+	}
+	return md
+}
+
+// moduleFromManifest retourne l'identité (ID, titre, URL, dossier, parent)
+// d'un module du manifest ; previousModule complète le reste.
+func moduleFromManifest(pm *ManifestModule) ModuleData {
+	return ModuleData{ID: pm.ID, Title: pm.Title, URL: pm.URL, Dir: pm.Dir, ParentID: pm.ParentID, Set: pm.Set}
+}
+
+// PreviousCourse retourne le cours courseURL tel qu'exporté au run précédent,
+// pour garder dans l'index un cours non retraité (ex. écarté par un filtre).
+func (e *Exporter) PreviousCourse(courseURL string) (CourseData, bool) {
+	pc := e.previous.Course(courseURL)
+	if pc == nil {
+		return CourseData{}, false
+	}
+	cd := CourseData{ID: pc.ID, Title: pc.Title, URL: pc.URL, Dir: pc.Dir}
+	cd.Modules = e.previousTree(pc.Modules, "")
+	return cd, true
+}
+
+// previousTree reconstruit, depuis la liste à plat mods, les modules de parent
+// parentID et leurs descendants.
+func (e *Exporter) previousTree(mods []ManifestModule, parentID string) []ModuleData {
+	var out []ModuleData
+	for i := range mods {
+		if mods[i].ParentID != parentID {
+			continue
+		}
+		md := e.previousModule(moduleFromManifest(&mods[i]))
+		if md.Set {
+			md.Children = e.previousTree(mods, md.ID)
+		}
+		out = append(out, md)
+	}
+	return out
+}
+
+// WriteManifest écrit OutputDir/manifest.json pour all. sourceURL est l'URL
+// de la classroom (ou du cours) exportée. Les cours du manifest précédent
+// absents de all (non relus pendant ce run) sont conservés.
+func (e *Exporter) WriteManifest(sourceURL string, all []CourseData) error {
+	m := Manifest{
+		Version:     ManifestVersion,
+		GeneratedAt: time.Now().UTC(),
+		SourceURL:   sourceURL,
+	}
+	seen := map[string]bool{}
+	for _, c := range all {
+		mc := ManifestCourse{ID: c.ID, Title: c.Title, URL: c.URL, Dir: c.Dir}
+		mc.Modules = e.manifestModules(c.Modules)
+		m.Courses = append(m.Courses, mc)
+		seen[c.URL] = true
+	}
+	if e.previous != nil {
+		for _, pc := range e.previous.Courses {
+			if !seen[pc.URL] {
+				m.Courses = append(m.Courses, pc)
+			}
+		}
+	}
+	return m.Save(e.OutputDir)
+}
+
+// manifestModules met l'arbre mods à plat, chaque set suivi de ses modules.
+func (e *Exporter) manifestModules(mods []ModuleData) []ManifestModule {
+	var out []ManifestModule
+	for _, md := range mods {
+		out = append(out, e.manifestModule(md))
+		out = append(out, e.manifestModules(md.Children)...)
+	}
+	return out
+}
+
+func (e *Exporter) manifestModule(md ModuleData) ManifestModule {
+	mm := ManifestModule{
+		ID:              md.ID,
+		Title:           md.Title,
+		URL:             md.URL,
+		Dir:             md.Dir,
+		ParentID:        md.ParentID,
+		Set:             md.Set,
+		DescriptionRaw:  md.RawDescription,
+		DescriptionHTML: md.Description,
+		ExportedAt:      md.ExportedAt,
+		UpdatedAt:       md.UpdatedAt,
+		ContentHash:     md.ContentHash,
+		MissingVideos:   md.MissingVideos,
+	}
+	if md.Dir != "" && !md.Set {
+		html := md.Dir + "/module.html"
+		if fileExistsAndNonZero(filepath.Join(e.OutputDir, filepath.FromSlash(html))) {
+			mm.HTMLFile = html
+		}
+		mdFile := md.Dir + "/module.md"
+		if fileExistsAndNonZero(filepath.Join(e.OutputDir, filepath.FromSlash(mdFile))) {
+			mm.MarkdownFile = mdFile
+		}
+	}
+	for _, v := range md.Videos {
+		mf, err := e.manifestFile(v.Filename)
+		if err != nil {
+			continue
+		}
+		mf.SourceURL = v.Source
+		if mf.SourceURL == "" {
+			mf.SourceURL = v.URL
+		}
+		mf.DownloadURL = v.URL
+		mm.Videos = append(mm.Videos, mf)
+	}
+	for _, r := range md.Resources {
+		mr := ManifestResource{Title: r.Title, URL: r.URL}
+		if r.Filename != "" {
+			if mf, err := e.manifestFile(r.Filename); err == nil {
+				mf.SourceURL = r.URL
+				mr.File = &mf
+			}
+		}
+		mm.Resources = append(mm.Resources, mr)
+	}
+	for _, im := range md.Images {
+		mf, err := e.manifestFile(im.Filename)
+		if err != nil {
+			continue
+		}
+		mf.SourceURL = im.URL
+		mm.Images = append(mm.Images, mf)
+	}
+	return mm
+}
+
+// manifestFile décrit le fichier path. Le SHA-256 du run précédent est repris
+// si la taille et la date de modification n'ont pas changé.
+func (e *Exporter) manifestFile(path string) (ManifestFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	mf := ManifestFile{
+		Path:       e.rel(path),
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime().UTC().Truncate(time.Second),
+	}
+	if e.previous != nil {
+		if pf := e.previous.files[mf.Path]; pf != nil && pf.Size == mf.Size && pf.ModifiedAt.Equal(mf.ModifiedAt) && pf.SHA256 != "" {
+			mf.SHA256 = pf.SHA256
+			return mf, nil
+		}
+	}
+	mf.SHA256, err = fileSHA256(path)
+	return mf, err
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
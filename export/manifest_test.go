@@ -0,0 +1,229 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeFile crée dir/rel avec data et retourne son chemin.
+func writeFile(t *testing.T, dir, rel, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// reload relit le manifest de dir dans un nouvel Exporter.
+func reload(t *testing.T, dir string) *Exporter {
+	t.Helper()
+	e := New(nil, dir)
+	if err := e.LoadManifest(); err != nil {
+		t.Fatal(err)
+	}
+	if e.previous == nil {
+		t.Fatal("no manifest written")
+	}
+	return e
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	exported := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	course := CourseData{
+		ID:    "c1",
+		Title: "Course",
+		URL:   "https://www.skool.com/g/classroom/course",
+		Dir:   "01 - Course",
+		Modules: []ModuleData{{
+			ID:    "s1",
+			Title: "Set",
+			URL:   "https://www.skool.com/g/classroom/course?md=s1",
+			Dir:   "01 - Course/01 - Set",
+			Set:   true,
+			Children: []ModuleData{{
+				ID:             "m1",
+				Title:          "Lesson",
+				URL:            "https://www.skool.com/g/classroom/course?md=m1",
+				Dir:            "01 - Course/01 - Set/01 - Lesson",
+				RawDescription: `[v2][{"type":"paragraph"}]`,
+				Description:    "<p>x</p>",
+				Videos: []VideoRecord{{
+					URL:      "https://player.vimeo.com/video/1?h=abc",
+					Source:   "https://vimeo.com/1",
+					Filename: writeFile(t, dir, "01 - Course/01 - Set/01 - Lesson/Lesson.mp4", "video"),
+				}},
+				MissingVideos: []string{"https://youtu.be/gone"},
+				Resources: []ResourceRecord{
+					{Title: "Slides", URL: "https://e.com/s.pdf", Filename: writeFile(t, dir, "01 - Course/01 - Set/01 - Lesson/resources/s.pdf", "pdf")},
+					{Title: "Site", URL: "https://e.com"},
+				},
+				Images: []ImageRecord{{
+					URL:      "https://e.com/i.png",
+					Filename: writeFile(t, dir, "01 - Course/01 - Set/01 - Lesson/assets/i.png", "png"),
+				}},
+				ExportedAt:  exported,
+				UpdatedAt:   "2024-04-30T00:00:00Z",
+				ContentHash: "hash",
+				ParentID:    "s1",
+			}},
+		}},
+	}
+
+	if err := New(nil, dir).WriteManifest("https://www.skool.com/g/classroom", []CourseData{course}); err != nil {
+		t.Fatal(err)
+	}
+	e := reload(t, dir)
+	if e.previous.Version != ManifestVersion {
+		t.Errorf("Version = %d, want %d", e.previous.Version, ManifestVersion)
+	}
+	got, ok := e.PreviousCourse(course.URL)
+	if !ok {
+		t.Fatal("PreviousCourse: course not found")
+	}
+	if !reflect.DeepEqual(got, course) {
+		t.Errorf("PreviousCourse =\n%+v\nwant\n%+v", got, course)
+	}
+	pm := e.previous.Module("m1")
+	if pm == nil || pm.HTMLFile != "" || len(pm.Videos) != 1 || pm.Videos[0].SHA256 != sha256Hex("video") {
+		t.Errorf("Module(m1) = %+v", pm)
+	}
+	if _, ok := e.PreviousCourse("https://www.skool.com/g/classroom/other"); ok {
+		t.Error("PreviousCourse found an unknown course")
+	}
+}
+
+func TestManifestKeepsUnseenCourses(t *testing.T) {
+	dir := t.TempDir()
+	a := CourseData{ID: "a", Title: "A", URL: "https://www.skool.com/g/classroom/a", Dir: "01 - A"}
+	b := CourseData{ID: "b", Title: "B", URL: "https://www.skool.com/g/classroom/b", Dir: "02 - B",
+		Modules: []ModuleData{{ID: "mb", Title: "Lesson", URL: "https://www.skool.com/g/classroom/b?md=mb", Dir: "02 - B/01 - Lesson"}}}
+	if err := New(nil, dir).WriteManifest("", []CourseData{a, b}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Second run : seul A est relu, et renommé.
+	a.Title = "A2"
+	if err := reload(t, dir).WriteManifest("", []CourseData{a}); err != nil {
+		t.Fatal(err)
+	}
+	e := reload(t, dir)
+	var titles []string
+	for _, c := range e.previous.Courses {
+		titles = append(titles, c.Title)
+	}
+	if !reflect.DeepEqual(titles, []string{"A2", "B"}) {
+		t.Errorf("courses = %q, want [A2 B]", titles)
+	}
+	if got, ok := e.PreviousCourse(b.URL); !ok || !reflect.DeepEqual(got, b) {
+		t.Errorf("PreviousCourse(B) = %+v, %v, want %+v", got, ok, b)
+	}
+}
+
+func TestManifestV1Upgrade(t *testing.T) {
+	dir := t.TempDir()
+	// v1 : pas de sets, les modules sont tous au premier niveau.
+	writeFile(t, dir, ManifestName, `{
+  "version": 1,
+  "generatedAt": "2024-01-01T00:00:00Z",
+  "courses": [{
+    "title": "Course",
+    "url": "https://www.skool.com/g/classroom/course",
+    "dir": "01 - Course",
+    "modules": [
+      {"id": "m1", "title": "One", "url": "u1", "dir": "01 - Course/01 - One", "contentHash": "h1"},
+      {"id": "m2", "title": "Two", "url": "u2", "dir": "01 - Course/02 - Two"}
+    ]
+  }]
+}`)
+	e := reload(t, dir)
+	got, ok := e.PreviousCourse("https://www.skool.com/g/classroom/course")
+	if !ok {
+		t.Fatal("PreviousCourse: v1 course not found")
+	}
+	want := CourseData{Title: "Course", URL: "https://www.skool.com/g/classroom/course", Dir: "01 - Course", Modules: []ModuleData{
+		{ID: "m1", Title: "One", URL: "u1", Dir: "01 - Course/01 - One", ContentHash: "h1"},
+		{ID: "m2", Title: "Two", URL: "u2", Dir: "01 - Course/02 - Two"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviousCourse =\n%+v\nwant\n%+v", got, want)
+	}
+	if d := e.previous.dirOwner("01 - Course/02 - Two"); d != "m2" {
+		t.Errorf("dirOwner = %q, want m2", d)
+	}
+
+	// Réécrit en v2, avec le set relu sur Skool.
+	got.Modules = []ModuleData{{ID: "s1", Title: "Set", Dir: "01 - Course/01 - Set", Set: true,
+		Children: []ModuleData{{ID: "m1", Title: "One", URL: "u1", Dir: "01 - Course/01 - Set/01 - One", ParentID: "s1"}}}}
+	if err := e.WriteManifest("", []CourseData{got}); err != nil {
+		t.Fatal(err)
+	}
+	m := reload(t, dir).previous
+	if m.Version != ManifestVersion {
+		t.Errorf("Version = %d, want %d", m.Version, ManifestVersion)
+	}
+	if s := m.Module("s1"); s == nil || !s.Set {
+		t.Errorf("Module(s1) = %+v, want a set", s)
+	}
+	if m1 := m.Module("m1"); m1 == nil || m1.ParentID != "s1" {
+		t.Errorf("Module(m1) = %+v, want ParentID s1", m1)
+	}
+
+	writeFile(t, dir, ManifestName, `{"version": 99, "courses": []}`)
+	if _, err := LoadManifest(dir); err == nil {
+		t.Error("LoadManifest accepted a newer version")
+	}
+}
+
+func TestManifestFileReusesSHA256(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "c/v.mp4", "video")
+	e := New(nil, dir)
+	mf, err := e.manifestFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Path != "c/v.mp4" || mf.Size != 5 || mf.SHA256 != sha256Hex("video") {
+		t.Fatalf("manifestFile = %+v", mf)
+	}
+
+	// Taille et date inchangées : le SHA-256 du run précédent est repris
+	// sans relire le fichier.
+	prev := mf
+	prev.SHA256 = "previous"
+	e.previous = &Manifest{files: map[string]*ManifestFile{prev.Path: &prev}}
+	if mf, _ := e.manifestFile(path); mf.SHA256 != "previous" {
+		t.Errorf("unchanged file: SHA256 = %q, want previous", mf.SHA256)
+	}
+
+	// Date changée : recalculé.
+	os.Chtimes(path, time.Time{}, prev.ModifiedAt.Add(time.Hour))
+	if mf, _ := e.manifestFile(path); mf.SHA256 != sha256Hex("video") {
+		t.Errorf("touched file: SHA256 = %q, want recomputed", mf.SHA256)
+	}
+
+	// Taille changée : recalculé.
+	os.WriteFile(path, []byte("video2"), 0o644)
+	os.Chtimes(path, time.Time{}, prev.ModifiedAt)
+	if mf, _ := e.manifestFile(path); mf.SHA256 != sha256Hex("video2") {
+		t.Errorf("resized file: SHA256 = %q, want recomputed", mf.SHA256)
+	}
+
+	if _, err := e.manifestFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("manifestFile succeeded on a missing file")
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
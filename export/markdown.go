@@ -0,0 +1,146 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"skool-video-dl/tiptap"
+)
+
+// -----------------------------------------------------------------------------
+// Markdown => module.md (front-matter YAML) + README.md global
+// -----------------------------------------------------------------------------
+
+// BuildModuleMarkdown écrit la page Markdown du module md avec les libellés
+// par défaut.
+func BuildModuleMarkdown(path, course string, md ModuleData) error {
+	return DefaultTemplates().BuildModuleMarkdown(path, course, md)
+}
+
+// BuildModuleMarkdown écrit la page Markdown du module md du cours course :
+// front-matter YAML (cours, module, URL source, fichiers), description
+// convertie depuis le Tiptap brut, vidéos et ressources. Les titres viennent
+// de labels.html (voir Label).
+func (t *Templates) BuildModuleMarkdown(path, course string, md ModuleData) error {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "course: %s\n", yamlString(course))
+	fmt.Fprintf(&sb, "module: %s\n", yamlString(md.Title))
+	if md.ID != "" {
+		fmt.Fprintf(&sb, "id: %s\n", yamlString(md.ID))
+	}
+	fmt.Fprintf(&sb, "source: %s\n", yamlString(md.URL))
+	if !md.ExportedAt.IsZero() {
+		fmt.Fprintf(&sb, "exported: %s\n", md.ExportedAt.UTC().Format(time.RFC3339))
+	}
+	writeYAMLList(&sb, "videos", videoNames(md.Videos))
+	var files []string
+	for _, r := range md.Resources {
+		if r.Filename != "" {
+			files = append(files, filepath.Base(r.Filename))
+		}
+	}
+	writeYAMLList(&sb, "resources", files)
+	sb.WriteString("---\n\n")
+
+	fmt.Fprintf(&sb, "# %s\n\n", tiptap.EscapeMarkdown(md.Title))
+	desc := tiptap.Renderer{
+		ImageSrc:    localImageSrc(md.Images),
+		Unsupported: t.Label("unsupported"),
+	}.DescriptionMarkdown(md.RawDescription)
+	if desc != "" {
+		sb.WriteString(desc + "\n\n")
+	}
+	if len(md.Videos) > 0 {
+		fmt.Fprintf(&sb, "## %s\n\n", tiptap.EscapeMarkdown(t.Label("videos")))
+		for _, name := range videoNames(md.Videos) {
+			fmt.Fprintf(&sb, "- [%s](%s)\n", tiptap.EscapeMarkdown(name), url.PathEscape(name))
+		}
+		sb.WriteString("\n")
+	}
+	if len(md.Resources) > 0 {
+		fmt.Fprintf(&sb, "## %s\n\n", tiptap.EscapeMarkdown(t.Label("resources")))
+		for _, r := range md.Resources {
+			link := r.URL
+			if r.Filename != "" {
+				link = url.PathEscape(filepath.Base(r.Filename))
+			}
+			fmt.Fprintf(&sb, "- [%s](%s)\n", tiptap.EscapeMarkdown(r.Title), link)
+		}
+		sb.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(strings.TrimRight(sb.String(), "\n")+"\n"), 0o644)
+}
+
+// BuildMarkdownIndex écrit outDir/README.md avec les libellés par défaut.
+func BuildMarkdownIndex(all []CourseData, outDir string) error {
+	return DefaultTemplates().BuildMarkdownIndex(all, outDir)
+}
+
+// BuildMarkdownIndex écrit outDir/README.md : un lien vers le module.md de
+// chaque module, regroupés par cours et par set.
+func (t *Templates) BuildMarkdownIndex(all []CourseData, outDir string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", tiptap.EscapeMarkdown(t.Label("title")))
+	for _, c := range all {
+		fmt.Fprintf(&sb, "\n## %s\n\n", tiptap.EscapeMarkdown(c.Title))
+		writeMarkdownModules(&sb, c.Modules, "")
+	}
+	return os.WriteFile(filepath.Join(outDir, "README.md"), []byte(sb.String()), 0o644)
+}
+
+// writeMarkdownModules écrit la liste des modules mods, liés à leur module.md
+// dans leur dossier (Dir) ; une leçon sans Dir est listée sans lien.
+func writeMarkdownModules(sb *strings.Builder, mods []ModuleData, indent string) {
+	for _, m := range mods {
+		switch {
+		case m.Set:
+			fmt.Fprintf(sb, "%s- **%s**\n", indent, tiptap.EscapeMarkdown(m.Title))
+			writeMarkdownModules(sb, m.Children, indent+"  ")
+		case m.Dir == "":
+			fmt.Fprintf(sb, "%s- %s\n", indent, tiptap.EscapeMarkdown(m.Title))
+		default:
+			fmt.Fprintf(sb, "%s- [%s](%s)\n", indent, tiptap.EscapeMarkdown(m.Title), escapePath(path.Join(m.Dir, "module.md")))
+		}
+	}
+}
+
+// escapePath encode chaque segment de p pour un lien relatif.
+func escapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, s := range parts {
+		parts[i] = url.PathEscape(s)
+	}
+	return strings.Join(parts, "/")
+}
+
+func videoNames(videos []VideoRecord) []string {
+	var out []string
+	for _, v := range videos {
+		out = append(out, filepath.Base(v.Filename))
+	}
+	return out
+}
+
+// yamlString retourne s en chaîne YAML entre guillemets (le JSON en est un
+// sous-ensemble).
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func writeYAMLList(sb *strings.Builder, key string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "%s:\n", key)
+	for _, it := range items {
+		fmt.Fprintf(sb, "  - %s\n", yamlString(it))
+	}
+}
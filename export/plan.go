@@ -0,0 +1,251 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"skool-video-dl/skool"
+	"skool-video-dl/tiptap"
+)
+
+// -----------------------------------------------------------------------------
+// Plan (dry-run) => ce qui serait exporté, sans rien écrire
+// -----------------------------------------------------------------------------
+
+// Plan décrit ce qu'un export produirait, avec ce qui existe déjà localement.
+type Plan struct {
+	SourceURL string       `json:"sourceUrl"`
+	OutputDir string       `json:"outputDir"`
+	Courses   []PlanCourse `json:"courses"`
+}
+
+// PlanCourse est un cours du plan.
+type PlanCourse struct {
+	ID      string       `json:"id,omitempty"`
+	Title   string       `json:"title"`
+	URL     string       `json:"url"`
+	Dir     string       `json:"dir"`
+	Error   string       `json:"error,omitempty"`
+	Modules []PlanModule `json:"modules"`
+}
+
+// PlanModule est une leçon du plan ; Sets liste les titres des sets qui la
+// contiennent. State vaut "added", "changed" ou "unchanged" par rapport au
+// manifest précédent.
+type PlanModule struct {
+	ID         string         `json:"id"`
+	Title      string         `json:"title"`
+	URL        string         `json:"url"`
+	Dir        string         `json:"dir"`
+	Sets       []string       `json:"sets,omitempty"`
+	State      string         `json:"state"`
+	HTMLExists bool           `json:"htmlExists"`
+	Error      string         `json:"error,omitempty"`
+	Videos     []PlanVideo    `json:"videos"`
+	Resources  []PlanResource `json:"resources,omitempty"`
+	Images     []PlanImage    `json:"images,omitempty"`
+}
+
+// PlanVideo est une vidéo du plan : lien trouvé, URL qui seraient essayées
+// (dans l'ordre, avec leur backend) et fichier local visé.
+type PlanVideo struct {
+	Source     string          `json:"source"`
+	Candidates []PlanCandidate `json:"candidates"`
+	File       string          `json:"file"`
+	Exists     bool            `json:"exists"`
+}
+
+// PlanCandidate est une URL que le backend Backend essaierait (voir
+// Downloader.Resolve).
+type PlanCandidate struct {
+	Backend string `json:"backend"`
+	URL     string `json:"url"`
+}
+
+// PlanResource est une ressource du plan. File est vide pour un simple lien,
+// qui ne serait pas téléchargé.
+type PlanResource struct {
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	File   string `json:"file,omitempty"`
+	Exists bool   `json:"exists"`
+}
+
+// PlanImage est une image de la description qui serait copiée localement.
+type PlanImage struct {
+	URL    string `json:"url"`
+	File   string `json:"file"`
+	Exists bool   `json:"exists"`
+}
+
+var stateNames = map[moduleState]string{
+	moduleAdded:     "added",
+	moduleChanged:   "changed",
+	moduleUnchanged: "unchanged",
+}
+
+// PlanCourse lit le cours c et ses modules retenus par Filter (Concurrency
+// pages à la fois) et résout leurs liens vidéo, sans rien télécharger ni
+// écrire.
+func (e *Exporter) PlanCourse(ctx context.Context, c skool.Course) (PlanCourse, error) {
+	e.init()
+	courseRel := e.courseRel(c)
+	courseDir := filepath.Join(e.OutputDir, courseRel)
+	pc := PlanCourse{ID: c.ID, Title: c.Title, URL: c.URL, Dir: courseRel}
+
+	mods, err := e.Client.Modules(ctx, c.URL)
+	if err != nil {
+		return pc, fmt.Errorf("cannot list modules: %w", err)
+	}
+	var kept []lessonJob
+	for j, job := range e.lessonJobs(mods, courseDir) {
+		if e.Filter.KeepModule(c, j, job.m) {
+			kept = append(kept, job)
+		}
+	}
+	pc.Modules = make([]PlanModule, len(kept))
+	forEach(len(kept), e.Concurrency, func(j int) {
+		pc.Modules[j] = e.planModule(ctx, kept[j])
+	})
+	return pc, nil
+}
+
+func (e *Exporter) planModule(ctx context.Context, job lessonJob) PlanModule {
+	m := job.m
+	modDir := e.moduleDir(job.dir, m)
+	pm := PlanModule{
+		ID:         m.ID,
+		Title:      m.Title,
+		URL:        m.URL,
+		Dir:        e.rel(modDir),
+		Sets:       job.sets,
+		State:      stateNames[e.moduleState(m, modDir)],
+		HTMLExists: fileExistsAndNonZero(filepath.Join(modDir, "module.html")),
+		Videos:     []PlanVideo{},
+	}
+
+	e.pages <- struct{}{}
+	lesson, err := e.Client.Lesson(ctx, m)
+	<-e.pages
+	if err != nil {
+		pm.Error = err.Error()
+		return pm
+	}
+
+	for i, link := range e.videoLinks(lesson, moduleLog{e: e, prefix: "    "}) {
+		candidates := []PlanCandidate{}
+		for _, name := range e.backendsFor(link) {
+			d := e.backend(name, m.URL, io.Discard)
+			if d == nil {
+				continue
+			}
+			urls, err := d.Resolve(ctx, link)
+			if err != nil {
+				continue
+			}
+			for _, u := range urls {
+				candidates = append(candidates, PlanCandidate{Backend: name, URL: u})
+			}
+		}
+		file, exists := e.previousVideo(m.ID, link), true
+		if file == "" {
+			file = existingVideo(modDir, m.Title, i+1)
+		}
+		if file == "" {
+			file, exists = videoFile(modDir, m.Title, i+1), false
+		}
+		pm.Videos = append(pm.Videos, PlanVideo{
+			Source:     link,
+			Candidates: candidates,
+			File:       e.rel(file),
+			Exists:     exists,
+		})
+	}
+	used := map[string]bool{}
+	for _, r := range lesson.Resources {
+		pr := PlanResource{Title: r.Title, URL: r.URL}
+		if r.IsFile() {
+			file := filepath.Join(modDir, resourceFileName(r, used))
+			pr.File = e.rel(file)
+			pr.Exists = fileExistsAndNonZero(file)
+		}
+		pm.Resources = append(pm.Resources, pr)
+	}
+	usedImages := map[string]bool{}
+	for _, src := range tiptap.ImageURLs(lesson.Description) {
+		if !isHTTPURL(src) {
+			continue
+		}
+		file := filepath.Join(modDir, assetsDir, uniqueFileName(imageFileName(src), "image", usedImages))
+		pm.Images = append(pm.Images, PlanImage{URL: src, File: e.rel(file), Exists: fileExistsAndNonZero(file)})
+	}
+	return pm
+}
+
+// WriteText affiche le plan sous forme d'arbre lisible.
+func (p Plan) WriteText(w io.Writer) {
+	var modules, videos, existing int
+	for _, c := range p.Courses {
+		fmt.Fprintf(w, "\n📘 %s (%d module(s)) → %s/\n", c.Title, len(c.Modules), c.Dir)
+		if c.Error != "" {
+			fmt.Fprintf(w, "  ⚠️  %s\n", c.Error)
+		}
+		for j, m := range c.Modules {
+			modules++
+			title := strings.Join(append(slices.Clip(m.Sets), m.Title), " › ")
+			fmt.Fprintf(w, "  [%d/%d] %s  [%s]\n", j+1, len(c.Modules), title, m.State)
+			if m.Error != "" {
+				fmt.Fprintf(w, "      ⚠️  %s\n", m.Error)
+			}
+			for _, v := range m.Videos {
+				videos++
+				status := "to download"
+				if v.Exists {
+					existing++
+					status = "exists"
+				}
+				fmt.Fprintf(w, "      🎬 %s → %s (%s)\n", v.Source, filepath.Base(v.File), status)
+			}
+			for _, r := range m.Resources {
+				if r.File == "" {
+					fmt.Fprintf(w, "      🔗 %s (link)\n", r.URL)
+					continue
+				}
+				status := "to download"
+				if r.Exists {
+					status = "exists"
+				}
+				fmt.Fprintf(w, "      📎 %s → %s (%s)\n", r.URL, filepath.Base(r.File), status)
+			}
+			for _, im := range m.Images {
+				status := "to download"
+				if im.Exists {
+					status = "exists"
+				}
+				fmt.Fprintf(w, "      🖼️  %s → %s (%s)\n", im.URL, im.File, status)
+			}
+		}
+	}
+	fmt.Fprintf(w, "\n📋 %d course(s), %d module(s), %d video(s) (%d already downloaded)\n",
+		len(p.Courses), modules, videos, existing)
+}
+
+// WriteJSON écrit le plan en JSON dans path ("-" pour la sortie standard).
+func (p Plan) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
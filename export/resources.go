@@ -0,0 +1,113 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"skool-video-dl/skool"
+)
+
+// -----------------------------------------------------------------------------
+// Ressources => fichiers joints téléchargés dans le dossier du module
+// -----------------------------------------------------------------------------
+
+// DownloadFile télécharge url dans outDir/name avec client. Un fichier déjà
+// présent et non vide n'est pas retéléchargé ; un téléchargement interrompu
+// ne laisse pas de fichier partiel.
+func DownloadFile(ctx context.Context, client *http.Client, url, outDir, name string) (string, error) {
+	final := filepath.Join(outDir, name)
+	if fileExistsAndNonZero(final) {
+		return final, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(outDir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return final, os.Rename(tmp.Name(), final)
+}
+
+// resourceFileName retourne un nom de fichier sûr pour r, distinct des noms
+// déjà pris dans used.
+func resourceFileName(r skool.Resource, used map[string]bool) string {
+	return uniqueFileName(r.FileName, "resource", used)
+}
+
+// uniqueFileName nettoie name ("fallback" s'il est vide ou fait de points) et
+// le rend distinct des noms déjà pris dans used : "notes.pdf", "notes
+// (2).pdf"...
+func uniqueFileName(name, fallback string, used map[string]bool) string {
+	ext := filepath.Ext(name)
+	base := Clean(strings.TrimSuffix(name, ext))
+	// "." ou ".." (URL en "/..") désigneraient le dossier ou son parent.
+	if emptyName(base) {
+		base = fallback
+	}
+	if ext = Clean(ext); ext == "." {
+		ext = ""
+	}
+	name = base + ext
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+func (e *Exporter) httpClient() *http.Client {
+	if e.HTTPClient != nil {
+		return e.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// downloadResources télécharge les ressources fichiers de la leçon dans modDir
+// sous leur nom d'origine ; les simples liens sont gardés tels quels. Les
+// ResourceRecord suivent l'ordre de resources.
+func (e *Exporter) downloadResources(ctx context.Context, resources []skool.Resource, modDir string, ml moduleLog) []ResourceRecord {
+	client := e.httpClient()
+	used := map[string]bool{}
+	var recs []ResourceRecord
+	for _, r := range resources {
+		rec := ResourceRecord{Title: r.Title, URL: r.URL}
+		if r.IsFile() {
+			name := resourceFileName(r, used)
+			ml.printf("downloading resource => %s\n", name)
+			e.downloads <- struct{}{}
+			fn, err := DownloadFile(ctx, client, r.URL, modDir, name)
+			<-e.downloads
+			if err != nil {
+				ml.printf("  ⚠️  fail dl: %v\n", err)
+			} else {
+				rec.Filename = fn
+			}
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"encoding/json"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Recherche => search-index.js, chargé par <script> (fonctionne en file://)
+// -----------------------------------------------------------------------------
+
+const searchIndexName = "search-index.js"
+
+// searchEntry est une leçon dans l'index de recherche. Les clés sont courtes :
+// l'index contient le texte de toutes les descriptions.
+type searchEntry struct {
+	// Title est le titre de la leçon, Path "cours › set".
+	Title string `json:"t"`
+	Path  string `json:"p"`
+	// URL est le lien vers la page, depuis la racine de l'export.
+	URL string `json:"u"`
+	// Text est le texte de la description rendue.
+	Text string `json:"x"`
+}
+
+// writeSearchIndex écrit dans path l'index de recherche des cours all :
+// un script qui définit window.SKOOL_SEARCH. Un fichier JSON ne pourrait pas
+// être lu par fetch() depuis file://.
+func writeSearchIndex(path string, all []CourseData) error {
+	entries := []searchEntry{}
+	for _, c := range all {
+		for _, l := range courseLessons(c.Modules, nil) {
+			if l.md.Dir == "" {
+				// Pas de page : la leçon n'a jamais été exportée.
+				continue
+			}
+			entries = append(entries, searchEntry{
+				Title: l.md.Title,
+				Path:  strings.Join(append([]string{c.Title}, l.sets...), " › "),
+				URL:   lessonHref(l.md),
+				Text:  htmlText(l.md.Description),
+			})
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte("window.SKOOL_SEARCH = "+string(data)+";\n"), 0o644)
+}
+
+var (
+	tagRe   = regexp.MustCompile(`<[^>]*>`)
+	spaceRe = regexp.MustCompile(`\s+`)
+)
+
+// htmlText retourne le texte d'un fragment HTML, espaces normalisés.
+func htmlText(s string) string {
+	s = tagRe.ReplaceAllString(s, " ")
+	return strings.TrimSpace(spaceRe.ReplaceAllString(html.UnescapeString(s), " "))
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"path/filepath"
+	"slices"
+
+	"skool-video-dl/skool"
+)
+
+// -----------------------------------------------------------------------------
+// Sync incrémentale => comparaison avec le manifest précédent
+// -----------------------------------------------------------------------------
+
+// SyncReport liste, pour un run, les modules ajoutés, modifiés, inchangés et
+// supprimés par rapport au manifest précédent ("Cours / Module").
+type SyncReport struct {
+	Added     []string
+	Changed   []string
+	Unchanged []string
+	Removed   []string
+}
+
+// moduleState est l'état d'un module par rapport au run précédent.
+type moduleState int
+
+const (
+	moduleAdded moduleState = iota
+	moduleChanged
+	moduleUnchanged
+)
+
+// moduleState compare m au manifest précédent. Un module n'est inchangé que
+// si son hash de contenu est identique, que ses pages (une par format) et ses
+// vidéos existent toutes et qu'aucune vidéo n'a échoué au run précédent
+// (MissingVideos) : sinon il est relu et les vidéos manquantes retentées.
+func (e *Exporter) moduleState(m skool.ModuleInfo, modDir string) moduleState {
+	pm := e.previous.Module(m.ID)
+	switch {
+	case pm == nil:
+		return moduleAdded
+	case pm.ContentHash == "" || pm.ContentHash != m.Hash, len(pm.MissingVideos) > 0:
+		return moduleChanged
+	}
+	for _, v := range pm.Videos {
+		if !fileExistsAndNonZero(filepath.Join(e.OutputDir, filepath.FromSlash(v.Path))) {
+			return moduleChanged
+		}
+	}
+	for _, page := range e.pageFiles() {
+		if !fileExistsAndNonZero(filepath.Join(modDir, page)) {
+			return moduleChanged
+		}
+	}
+	return moduleUnchanged
+}
+
+// record ajoute un module au rapport de synchronisation.
+func (e *Exporter) record(st moduleState, course, module string) {
+	name := course + " / " + module
+	e.reportMu.Lock()
+	defer e.reportMu.Unlock()
+	switch st {
+	case moduleAdded:
+		e.report.Added = append(e.report.Added, name)
+	case moduleChanged:
+		e.report.Changed = append(e.report.Changed, name)
+	case moduleUnchanged:
+		e.report.Unchanged = append(e.report.Unchanged, name)
+	}
+}
+
+// recordRemoved ajoute au rapport les leçons du cours courseURL présentes au
+// run précédent mais plus listées par Skool (mods : voir skool.Lessons).
+func (e *Exporter) recordRemoved(courseURL, course string, mods []skool.ModuleInfo) {
+	pc := e.previous.Course(courseURL)
+	if pc == nil {
+		return
+	}
+	current := map[string]bool{}
+	for _, m := range mods {
+		current[m.ID] = true
+	}
+	e.reportMu.Lock()
+	defer e.reportMu.Unlock()
+	for _, pm := range pc.Modules {
+		if !pm.Set && !current[pm.ID] {
+			e.report.Removed = append(e.report.Removed, course+" / "+pm.Title)
+		}
+	}
+}
+
+// Report retourne le rapport de synchronisation des cours exportés jusqu'ici,
+// chaque liste triée.
+func (e *Exporter) Report() SyncReport {
+	e.reportMu.Lock()
+	defer e.reportMu.Unlock()
+	r := SyncReport{
+		Added:     slices.Sorted(slices.Values(e.report.Added)),
+		Changed:   slices.Sorted(slices.Values(e.report.Changed)),
+		Unchanged: slices.Sorted(slices.Values(e.report.Unchanged)),
+		Removed:   slices.Sorted(slices.Values(e.report.Removed)),
+	}
+	return r
+}
@@ -0,0 +1,86 @@
+(function () {
+  var root = window.SKOOL_ROOT || "";
+  // Les textes affichés viennent des attributs data-label et data-empty,
+  // rendus depuis labels.html.
+
+  // Progression : une clé localStorage par leçon terminée.
+  function key(id) { return "skool-done:" + id; }
+  function isDone(id) {
+    try { return localStorage.getItem(key(id)) === "1"; } catch (e) { return false; }
+  }
+  function setDone(id, done) {
+    try {
+      if (done) { localStorage.setItem(key(id), "1"); } else { localStorage.removeItem(key(id)); }
+    } catch (e) {}
+  }
+  function refresh() {
+    document.querySelectorAll("[data-id]").forEach(function (el) {
+      el.classList.toggle("done", isDone(el.getAttribute("data-id")));
+    });
+    document.querySelectorAll("input[data-done]").forEach(function (cb) {
+      cb.checked = isDone(cb.getAttribute("data-done"));
+    });
+    document.querySelectorAll("[data-progress]").forEach(function (el) {
+      var ids = el.getAttribute("data-progress").split(" ").filter(Boolean);
+      var n = ids.filter(isDone).length;
+      el.textContent = n + " / " + ids.length + " " + (el.getAttribute("data-label") || "");
+    });
+  }
+  document.querySelectorAll("input[data-done]").forEach(function (cb) {
+    cb.addEventListener("change", function () {
+      setDone(cb.getAttribute("data-done"), cb.checked);
+      refresh();
+    });
+  });
+  window.addEventListener("storage", refresh);
+  refresh();
+
+  // Recherche plein texte dans window.SKOOL_SEARCH (search-index.js).
+  var accents = new RegExp("[\\u0300-\\u036f]", "g");
+  function norm(s) { return s.toLowerCase().normalize("NFD").replace(accents, ""); }
+  var index = (window.SKOOL_SEARCH || []).map(function (e) {
+    return { e: e, title: norm(e.t), text: norm(e.x) };
+  });
+  function snippet(text, normText, term) {
+    var i = normText.indexOf(term);
+    if (i < 0) { return text.slice(0, 140); }
+    var start = Math.max(0, i - 60);
+    return (start > 0 ? "…" : "") + text.slice(start, start + 160) + "…";
+  }
+  document.querySelectorAll(".search").forEach(function (box) {
+    var input = box.querySelector("input");
+    var list = box.querySelector(".search-results");
+    input.addEventListener("input", function () {
+      list.textContent = "";
+      var terms = norm(input.value).split(" ").filter(Boolean);
+      if (terms.length === 0) { return; }
+      var hits = index.filter(function (r) {
+        return terms.every(function (t) { return r.title.indexOf(t) >= 0 || r.text.indexOf(t) >= 0; });
+      });
+      hits.sort(function (a, b) {
+        return (b.title.indexOf(terms[0]) >= 0) - (a.title.indexOf(terms[0]) >= 0);
+      });
+      if (hits.length === 0) {
+        var none = document.createElement("li");
+        none.className = "empty";
+        none.textContent = list.getAttribute("data-empty") || "";
+        list.appendChild(none);
+        return;
+      }
+      hits.slice(0, 30).forEach(function (r) {
+        var li = document.createElement("li");
+        var a = document.createElement("a");
+        a.href = root + r.e.u;
+        a.textContent = r.e.t;
+        var path = document.createElement("small");
+        path.textContent = r.e.p;
+        var p = document.createElement("p");
+        p.textContent = snippet(r.e.x, r.text, terms[0]);
+        li.appendChild(a);
+        li.appendChild(path);
+        li.appendChild(p);
+        list.appendChild(li);
+      });
+    });
+  });
+})();
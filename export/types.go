@@ -0,0 +1,71 @@
+// Package export télécharge le contenu des modules Skool et génère la copie
+// hors ligne (pages HTML, vidéos, index).
+package export
+
+import "time"
+
+// CourseData est un cours exporté avec ses modules. Modules ne contient que le
+// premier niveau ; les leçons d'un set sont dans ses Children.
+type CourseData struct {
+	ID    string
+	Title string
+	URL   string
+	// Dir est le dossier du cours, relatif au dossier d'export.
+	Dir     string
+	Modules []ModuleData
+}
+
+// ModuleData est un module exporté.
+type ModuleData struct {
+	ID    string
+	Title string
+	URL   string
+	// Dir est le dossier du module, relatif au dossier d'export.
+	Dir string
+	// RawDescription est la description Tiptap telle que lue sur Skool.
+	RawDescription string
+	// Description est la description rendue en HTML ; les images copiées
+	// localement y pointent vers assets/ (relatif au dossier du module).
+	Description string
+	Videos      []VideoRecord
+	// MissingVideos sont les liens vidéo dont tous les téléchargements ont
+	// échoué ; le module est relu au run suivant pour les retenter.
+	MissingVideos []string
+	Resources     []ResourceRecord
+	Images        []ImageRecord
+	// ExportedAt est la date à laquelle le module a été lu sur Skool.
+	ExportedAt time.Time
+	// UpdatedAt et ContentHash identifient la version exportée (voir
+	// skool.ModuleInfo) ; ils servent à la synchronisation incrémentale.
+	UpdatedAt   string
+	ContentHash string
+	// ParentID est l'ID du set qui contient le module, "" au premier niveau.
+	ParentID string
+	// Set indique un set (dossier) : il n'a ni description ni vidéos, seulement
+	// les modules de Children, exportés dans son dossier.
+	Set      bool
+	Children []ModuleData
+}
+
+// VideoRecord est une vidéo téléchargée : URL source et fichier local.
+type VideoRecord struct {
+	// URL est l'URL effectivement téléchargée.
+	URL string
+	// Source est le lien trouvé dans le module (avant variantes Vimeo).
+	Source   string
+	Filename string
+}
+
+// ResourceRecord est une ressource jointe au module : fichier téléchargé, ou
+// simple lien si Filename est vide.
+type ResourceRecord struct {
+	Title    string
+	URL      string
+	Filename string
+}
+
+// ImageRecord est une image de la description copiée localement.
+type ImageRecord struct {
+	URL      string
+	Filename string
+}
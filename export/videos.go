@@ -0,0 +1,95 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Vidéos => noms de fichiers, reprise et types MIME
+// -----------------------------------------------------------------------------
+
+// videoTypes associe les extensions de vidéos reconnues à leur type MIME.
+var videoTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+	".mov":  "video/quicktime",
+	".ts":   "video/mp2t",
+}
+
+// videoMIME retourne le type MIME de la vidéo fn d'après son extension ; ""
+// si elle n'est pas reconnue.
+func videoMIME(fn string) string {
+	return videoTypes[strings.ToLower(filepath.Ext(fn))]
+}
+
+// videoBase retourne le nom sans extension de la vidéo n° idx d'une leçon :
+// "01 - Titre de la leçon", ou "video-01" sans titre.
+func videoBase(title string, idx int) string {
+	if emptyName(Clean(title)) {
+		return fmt.Sprintf("video-%02d", idx)
+	}
+	return dirName(idx, title, "")
+}
+
+// videoFile est le fichier attendu (MP4) pour la vidéo n° idx de la leçon
+// title dans outDir ; l'extension réelle dépend du téléchargement.
+func videoFile(outDir, title string, idx int) string {
+	return filepath.Join(outDir, videoBase(title, idx)+".mp4")
+}
+
+// existingVideo retourne la vidéo n° idx de la leçon title déjà téléchargée
+// dans outDir, quelle que soit son extension (voir findVideo) : sous son nom
+// "NN - <titre>" ou l'ancien nom video-NN ; "" si aucune. Une vidéo d'une
+// leçon renommée depuis est retrouvée par le manifest (voir previousVideo).
+func existingVideo(outDir, title string, idx int) string {
+	for _, base := range []string{videoBase(title, idx), videoBase("", idx)} {
+		if fn := findVideo(filepath.Join(outDir, base+".mp4")); fn != "" {
+			return fn
+		}
+	}
+	return ""
+}
+
+// previousVideo retourne le fichier, toujours présent, téléchargé pour le
+// lien link du module id au run précédent ; "" si aucun.
+func (e *Exporter) previousVideo(id, link string) string {
+	mod := e.previous.Module(id)
+	if mod == nil {
+		return ""
+	}
+	for _, v := range mod.Videos {
+		if v.SourceURL != link || v.Path == "" {
+			continue
+		}
+		if fn := filepath.Join(e.OutputDir, filepath.FromSlash(v.Path)); fileExistsAndNonZero(fn) {
+			return fn
+		}
+	}
+	return ""
+}
+
+// findVideo retourne la vidéo écrite sous le nom de path (sans extension)
+// dans son dossier, path en priorité ; "" si aucune.
+func findVideo(path string) string {
+	if fileExistsAndNonZero(path) {
+		return path
+	}
+	dir := filepath.Dir(path)
+	want := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	entries, _ := os.ReadDir(dir)
+	for _, en := range entries {
+		name := en.Name()
+		if en.IsDir() || videoMIME(name) == "" || strings.TrimSuffix(name, filepath.Ext(name)) != want {
+			continue
+		}
+		if fn := filepath.Join(dir, name); fileExistsAndNonZero(fn) {
+			return fn
+		}
+	}
+	return ""
+}
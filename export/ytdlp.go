@@ -0,0 +1,181 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"skool-video-dl/media"
+	"skool-video-dl/vimeo"
+)
+
+// -----------------------------------------------------------------------------
+// DownloadVideo => yt-dlp
+// -----------------------------------------------------------------------------
+
+// DownloadVideo télécharge url avec yt-dlp dans outDir sous le nom
+// video-<idx> et retourne le fichier écrit, avec l'extension choisie par
+// yt-dlp. Un fichier déjà présent et non vide n'est pas retéléchargé.
+// extraArgs sont passés à yt-dlp avant l'URL (ex. --cookies).
+func DownloadVideo(url string, outDir string, idx int, extraArgs ...string) (string, error) {
+	y := YTDLP{Args: extraArgs}
+	if fn := existingVideo(outDir, "", idx); fn != "" {
+		fmt.Fprintf(y.out(), "      skipping existing file %s\n", filepath.Base(fn))
+		return fn, nil
+	}
+	return y.Download(context.Background(), url, videoFile(outDir, "", idx), nil)
+}
+
+// YTDLP est le Downloader qui confie les vidéos à yt-dlp.
+type YTDLP struct {
+	// Args sont passés à yt-dlp avant l'URL (ex. --cookies).
+	Args []string
+	// Referer est la page qui intègre la vidéo (la leçon Skool) : les
+	// lecteurs Vimeo restreints à un domaine refusent les requêtes sans.
+	Referer string
+	// Out reçoit la sortie de yt-dlp et les messages de Download ; os.Stdout
+	// si nil.
+	Out io.Writer
+}
+
+func (y YTDLP) out() io.Writer {
+	if y.Out == nil {
+		return os.Stdout
+	}
+	return y.Out
+}
+
+// Name retourne "ytdlp".
+func (YTDLP) Name() string { return BackendYTDLP }
+
+// Resolve retourne toutes les variantes d'URL d'un lien Vimeo (voir
+// vimeo.AllURLs), link seul sinon.
+func (YTDLP) Resolve(_ context.Context, link string) ([]string, error) {
+	if vimeo.IsVimeo(link) {
+		return vimeo.AllURLs(link), nil
+	}
+	return []string{link}, nil
+}
+
+// ytdlpProgress est le format des lignes de progression demandées à yt-dlp :
+// octets reçus, taille totale et taille estimée ("NA" si inconnues).
+const ytdlpProgress = "download:" + ytdlpProgressPrefix +
+	"%(progress.downloaded_bytes)s %(progress.total_bytes)s %(progress.total_bytes_estimate)s"
+
+const ytdlpProgressPrefix = "[skool-progress] "
+
+// ytdlpFile fait afficher à yt-dlp le chemin final de la vidéo, une fois
+// les pistes fusionnées et le fichier renommé.
+const ytdlpFile = "after_move:" + ytdlpFilePrefix + "%(filepath)s"
+
+const ytdlpFilePrefix = "[skool-file] "
+
+// Download lance yt-dlp (3 tentatives) pour écrire u dans path, le nom de
+// path sans extension servant de modèle de sortie, et retourne le fichier
+// réellement écrit (.mp4, .webm, .mkv...). Avec progress, yt-dlp affiche une
+// ligne par étape, lue ici au lieu de sa barre de progression.
+func (y YTDLP) Download(ctx context.Context, u, path string, progress media.Progress) (string, error) {
+	outputTemplate := strings.TrimSuffix(path, filepath.Ext(path)) + ".%(ext)s"
+
+	// Retry logic for downloading videos
+	maxRetries := 3
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if attempt > 1 {
+			fmt.Fprintf(y.out(), "retrying download (attempt %d/%d)\n", attempt, maxRetries)
+			// Add a small delay between retries
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		// --print rend yt-dlp silencieux : --progress garde sa progression.
+		args := append([]string{"-o", outputTemplate, "--print", ytdlpFile, "--progress"}, y.Args...)
+		if y.Referer != "" {
+			args = append(args, "--add-header", "Referer:"+y.Referer)
+		}
+		if progress != nil {
+			args = append(args, "--newline", "--progress-template", ytdlpProgress)
+		}
+		cmd := exec.CommandContext(ctx, "yt-dlp", append(args, u)...)
+		cmd.Stderr = y.out()
+		file, err := runYTDLP(cmd, progress, y.out())
+		if err != nil {
+			if attempt == maxRetries || ctx.Err() != nil {
+				return "", err
+			}
+			fmt.Fprintf(y.out(), "download failed: %v, retrying...\n", err)
+			continue
+		}
+		if file == "" || !fileExistsAndNonZero(file) {
+			// yt-dlp n'a rien affiché (version ancienne) : on cherche le
+			// fichier sous le nom demandé, quelle que soit son extension.
+			file = findVideo(path)
+		}
+		if file == "" {
+			return "", fmt.Errorf("yt-dlp wrote no %s", filepath.Base(strings.TrimSuffix(path, filepath.Ext(path))))
+		}
+		return file, nil
+	}
+	return "", nil
+}
+
+// runYTDLP lance cmd en recopiant sa sortie sur w, sauf les lignes de
+// progression (voir ytdlpProgress), transmises à progress, et le chemin de la
+// vidéo (voir ytdlpFile), retourné.
+func runYTDLP(cmd *exec.Cmd, progress media.Progress, w io.Writer) (string, error) {
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	var file string
+	sc := bufio.NewScanner(out)
+	for sc.Scan() {
+		line := sc.Text()
+		if fn, ok := strings.CutPrefix(line, ytdlpFilePrefix); ok {
+			file = strings.TrimSpace(fn)
+			continue
+		}
+		if p, ok := strings.CutPrefix(line, ytdlpProgressPrefix); ok {
+			if done, total, ok := parseYTDLPProgress(p); ok && progress != nil {
+				progress(done, total)
+			}
+			continue
+		}
+		fmt.Fprintln(w, line)
+	}
+	io.Copy(io.Discard, out)
+	return file, cmd.Wait()
+}
+
+// parseYTDLPProgress lit une ligne "reçus total estimé" ; total vaut 0 si
+// yt-dlp ne connaît pas la taille.
+func parseYTDLPProgress(line string) (done, total int64, ok bool) {
+	f := strings.Fields(line)
+	if len(f) != 3 {
+		return 0, 0, false
+	}
+	num := func(s string) int64 {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0
+		}
+		return int64(v)
+	}
+	done = num(f[0])
+	if total = num(f[1]); total == 0 {
+		total = num(f[2])
+	}
+	return done, total, true
+}
@@ -2,10 +2,12 @@ module skool-video-dl
 
 go 1.24.2
 
-require github.com/chromedp/chromedp v0.13.6
+require (
+	github.com/chromedp/cdproto v0.0.0-20250429231605-6ed5b53462d4
+	github.com/chromedp/chromedp v0.13.6
+)
 
 require (
-	github.com/chromedp/cdproto v0.0.0-20250429231605-6ed5b53462d4 // indirect
 	github.com/chromedp/sysutil v1.1.0 // indirect
 	github.com/go-json-experiment/json v0.0.0-20250211171154-1ae217ad3535 // indirect
 	github.com/gobwas/httphead v0.1.0 // indirect
@@ -0,0 +1,145 @@
+// Package loom télécharge les vidéos Loom sans yt-dlp, via les endpoints
+// publics utilisés par le lecteur loom.com.
+package loom
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"skool-video-dl/media"
+)
+
+var reLoomID = regexp.MustCompile(`(?i)loom\.com/(?:share|embed|v)/(?:[^/?#]*-)?([0-9a-f]{32})(?:[/?#]|$)`)
+
+// VideoID extrait l'ID d'un lien loom.com/share/<id> (ou /embed/, /v/) ; ""
+// si link n'en est pas un.
+func VideoID(link string) string {
+	m := reLoomID.FindStringSubmatch(link)
+	if m == nil {
+		return ""
+	}
+	return strings.ToLower(m[1])
+}
+
+// -----------------------------------------------------------------------------
+// Client => ID => URL de la vidéo transcodée
+// -----------------------------------------------------------------------------
+
+// DefaultBaseURL est l'origine des endpoints Loom.
+const DefaultBaseURL = "https://www.loom.com"
+
+// Client résout et télécharge les vidéos Loom.
+type Client struct {
+	// HTTP fait les requêtes ; http.DefaultClient si nil.
+	HTTP *http.Client
+	// BaseURL remplace DefaultBaseURL (tests).
+	BaseURL string
+}
+
+func (c *Client) http() *http.Client {
+	if c != nil && c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) baseURL() string {
+	if c != nil && c.BaseURL != "" {
+		return strings.TrimRight(c.BaseURL, "/")
+	}
+	return DefaultBaseURL
+}
+
+// Resolve retourne l'URL de la vidéo id : le MP4 transcodé, sinon le fichier
+// original (raw-url). L'URL peut être une playlist HLS ; elle est signée et
+// expire au bout de quelques heures.
+func (c *Client) Resolve(ctx context.Context, id string) (string, error) {
+	var errs []error
+	for _, endpoint := range []string{"transcoded-url", "raw-url"} {
+		u, err := c.sessionURL(ctx, id, endpoint)
+		if err == nil {
+			return u, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", fmt.Errorf("cannot resolve Loom video %s: %w", id, errors.Join(errs...))
+}
+
+// sessionURL interroge /api/campaigns/sessions/<id>/<endpoint>.
+func (c *Client) sessionURL(ctx context.Context, id, endpoint string) (string, error) {
+	body, _ := json.Marshal(map[string]any{
+		"anonID":         anonID(),
+		"deviceID":       nil,
+		"force_original": false,
+		"password":       nil,
+	})
+	u := fmt.Sprintf("%s/api/campaigns/sessions/%s/%s", c.baseURL(), id, endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("POST %s: %s", endpoint, resp.Status)
+	}
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("POST %s: %w", endpoint, err)
+	}
+	if out.URL == "" {
+		return "", fmt.Errorf("POST %s: no url in response", endpoint)
+	}
+	return out.URL, nil
+}
+
+// anonID retourne un UUID v4 aléatoire, comme le lecteur Loom anonyme.
+func anonID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// -----------------------------------------------------------------------------
+// Download => MP4 ou HLS, avec reprise (voir media.Download)
+// -----------------------------------------------------------------------------
+
+// Download télécharge la vidéo Loom link dans path et retourne le fichier
+// écrit (voir media.Download : path, ou un .ts pour une vidéo HLS sans
+// ffmpeg). Les tentatives et la reprise sont celles de media ; un
+// téléchargement interrompu est repris au run suivant. progress peut être
+// nil.
+func (c *Client) Download(ctx context.Context, link, path string, progress media.Progress) (string, error) {
+	id := VideoID(link)
+	if id == "" {
+		return "", fmt.Errorf("not a Loom video link: %s", link)
+	}
+	src, err := c.Resolve(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if media.KindOf(src) == media.Unknown {
+		// URL sans extension reconnue : c'est un fichier.
+		if err := media.Fetch(ctx, c.http(), src, path, progress); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	return media.Download(ctx, c.http(), src, path, progress)
+}
@@ -0,0 +1,135 @@
+package loom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testID = "0123456789abcdef0123456789abcdef"
+
+// standIn simule les endpoints Loom et le CDN. transcoded et raw sont les
+// URL retournées par chaque endpoint ("" => 404), relatives au serveur.
+type standIn struct {
+	*httptest.Server
+	transcoded, raw string
+	video           []byte
+	ranges          []string
+}
+
+func newStandIn(t *testing.T, transcoded, raw string) *standIn {
+	s := &standIn{transcoded: transcoded, raw: raw, video: bytes.Repeat([]byte("loom-video-"), 1000)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/campaigns/sessions/{id}/{endpoint}", func(w http.ResponseWriter, r *http.Request) {
+		u := map[string]string{"transcoded-url": s.transcoded, "raw-url": s.raw}[r.PathValue("endpoint")]
+		if r.PathValue("id") != testID || u == "" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"url":%q}`, s.URL+u)
+	})
+	mux.HandleFunc("GET /cdn/video.mp4", func(w http.ResponseWriter, r *http.Request) {
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(s.video))
+	})
+	mux.HandleFunc("GET /cdn/playlist.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:4,\nseg1.m4s\n#EXTINF:4,\nseg2.m4s\n#EXT-X-ENDLIST\n")
+	})
+	mux.HandleFunc("GET /cdn/{seg}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "["+r.PathValue("seg")+"]")
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestVideoID(t *testing.T) {
+	for link, want := range map[string]string{
+		"https://www.loom.com/share/" + testID:                       testID,
+		"https://www.loom.com/share/" + testID + "?sid=x":            testID,
+		"https://loom.com/embed/" + testID:                           testID,
+		"https://www.loom.com/share/My-Lesson-Title-" + testID:       testID,
+		"https://www.loom.com/share/folder/" + testID + "/something": "",
+		"https://vimeo.com/123":                                      "",
+	} {
+		if got := VideoID(link); got != want {
+			t.Errorf("VideoID(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	s := newStandIn(t, "/cdn/video.mp4?sig=1", "")
+	path := filepath.Join(t.TempDir(), "video-01.mp4")
+	c := &Client{HTTP: s.Client(), BaseURL: s.URL}
+
+	fn, err := c.Download(context.Background(), "https://www.loom.com/share/"+testID, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn != path {
+		t.Errorf("Download wrote %q, want %q", fn, path)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, s.video) {
+		t.Errorf("downloaded %d bytes, want %d", len(got), len(s.video))
+	}
+}
+
+func TestDownloadRawFallback(t *testing.T) {
+	s := newStandIn(t, "", "/cdn/video.mp4")
+	path := filepath.Join(t.TempDir(), "video-01.mp4")
+	c := &Client{HTTP: s.Client(), BaseURL: s.URL}
+	if _, err := c.Download(context.Background(), "https://www.loom.com/share/"+testID, path, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	s := newStandIn(t, "/cdn/video.mp4", "")
+	path := filepath.Join(t.TempDir(), "video-01.mp4")
+	os.WriteFile(path+".part", s.video[:4000], 0o644)
+	c := &Client{HTTP: s.Client(), BaseURL: s.URL}
+
+	if _, err := c.Download(context.Background(), "https://www.loom.com/share/"+testID, path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.ranges) != 1 || s.ranges[0] != "bytes=4000-" {
+		t.Errorf("ranges = %q, want [bytes=4000-]", s.ranges)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, s.video) {
+		t.Errorf("resumed file differs (%d bytes, want %d)", len(got), len(s.video))
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Errorf("%s.part left behind", path)
+	}
+}
+
+func TestDownloadHLS(t *testing.T) {
+	s := newStandIn(t, "/cdn/playlist.m3u8?sig=1", "")
+	c := &Client{HTTP: s.Client(), BaseURL: s.URL}
+	path := filepath.Join(t.TempDir(), "v.mp4")
+	fn, err := c.Download(context.Background(), "https://www.loom.com/share/"+testID, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(fn)
+	if fn != path || string(got) != "[init.mp4][seg1.m4s][seg2.m4s]" {
+		t.Errorf("Download wrote %q = %q", fn, got)
+	}
+}
+
+func TestDownloadUnresolved(t *testing.T) {
+	s := newStandIn(t, "", "")
+	c := &Client{HTTP: s.Client(), BaseURL: s.URL}
+	if _, err := c.Download(context.Background(), "https://www.loom.com/share/"+testID, filepath.Join(t.TempDir(), "v.mp4"), nil); err == nil {
+		t.Error("Download succeeded without any source")
+	}
+}
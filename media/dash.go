@@ -0,0 +1,307 @@
+package media
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// DASH => MPD => meilleures pistes vidéo / audio => segments
+// -----------------------------------------------------------------------------
+
+type mpd struct {
+	Type     string      `xml:"type,attr"`
+	Duration string      `xml:"mediaPresentationDuration,attr"`
+	BaseURL  string      `xml:"BaseURL"`
+	Periods  []mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	Duration       string             `xml:"duration,attr"`
+	BaseURL        string             `xml:"BaseURL"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	MimeType        string              `xml:"mimeType,attr"`
+	ContentType     string              `xml:"contentType,attr"`
+	BaseURL         string              `xml:"BaseURL"`
+	SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *mpdSegmentList     `xml:"SegmentList"`
+	Representations []mpdRepresentation `xml:"Representation"`
+}
+
+type mpdRepresentation struct {
+	ID              string              `xml:"id,attr"`
+	Bandwidth       int                 `xml:"bandwidth,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	BaseURL         string              `xml:"BaseURL"`
+	SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *mpdSegmentList     `xml:"SegmentList"`
+}
+
+type mpdSegmentTemplate struct {
+	Initialization string `xml:"initialization,attr"`
+	Media          string `xml:"media,attr"`
+	StartNumber    *int64 `xml:"startNumber,attr"`
+	Timescale      int64  `xml:"timescale,attr"`
+	Duration       int64  `xml:"duration,attr"`
+	Timeline       []struct {
+		T *int64 `xml:"t,attr"`
+		D int64  `xml:"d,attr"`
+		R int64  `xml:"r,attr"`
+	} `xml:"SegmentTimeline>S"`
+}
+
+type mpdSegmentList struct {
+	Initialization *struct {
+		SourceURL string `xml:"sourceURL,attr"`
+	} `xml:"Initialization"`
+	SegmentURLs []struct {
+		Media string `xml:"media,attr"`
+	} `xml:"SegmentURL"`
+}
+
+// downloadDASH télécharge le manifest u dans path : la piste vidéo et la
+// piste audio de plus haut débit de la première période. Deux pistes
+// séparées sont fusionnées par ffmpeg, obligatoire dans ce cas.
+func downloadDASH(ctx context.Context, client *http.Client, u, path string, progress Progress) error {
+	b, err := get(ctx, client, u, "")
+	if err != nil {
+		return err
+	}
+	var m mpd
+	if err := xml.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("%s is not a DASH manifest: %w", u, err)
+	}
+	if m.Type == "dynamic" {
+		return fmt.Errorf("%w: live DASH stream", ErrUnsupported)
+	}
+	if len(m.Periods) == 0 {
+		return fmt.Errorf("no period in %s", u)
+	}
+	p := m.Periods[0]
+	duration := parseISODuration(p.Duration)
+	if duration == 0 {
+		duration = parseISODuration(m.Duration)
+	}
+	base, err := resolveAll(u, m.BaseURL, p.BaseURL)
+	if err != nil {
+		return err
+	}
+
+	var tracks [][]segment
+	for _, kind := range []string{"video", "audio"} {
+		set, rep := bestRepresentation(p.AdaptationSets, kind)
+		if rep == nil {
+			continue
+		}
+		segs, err := dashSegments(base, set, rep, duration)
+		if err != nil {
+			return err
+		}
+		tracks = append(tracks, segs)
+	}
+	switch len(tracks) {
+	case 0:
+		return fmt.Errorf("no video or audio track in %s", u)
+	case 1:
+		return downloadSegments(ctx, client, tracks[0], path, progress)
+	}
+	if !hasFFmpeg() {
+		return fmt.Errorf("DASH with separate audio: %w", errNoFFmpeg)
+	}
+	inputs := []string{path + ".video", path + ".audio"}
+	for i, segs := range tracks {
+		if err := downloadSegments(ctx, client, segs, inputs[i], progress); err != nil {
+			return err
+		}
+	}
+	if err := ffmpegCopy(ctx, path, inputs...); err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		os.Remove(in)
+	}
+	return nil
+}
+
+// bestRepresentation retourne la représentation de plus haut débit de type
+// kind ("video", "audio") et son AdaptationSet.
+func bestRepresentation(sets []mpdAdaptationSet, kind string) (*mpdAdaptationSet, *mpdRepresentation) {
+	var (
+		bestSet *mpdAdaptationSet
+		best    *mpdRepresentation
+	)
+	for i := range sets {
+		s := &sets[i]
+		for j := range s.Representations {
+			r := &s.Representations[j]
+			t := s.ContentType
+			if t == "" {
+				mime := r.MimeType
+				if mime == "" {
+					mime = s.MimeType
+				}
+				t, _, _ = strings.Cut(mime, "/")
+			}
+			if t == kind && (best == nil || r.Bandwidth > best.Bandwidth) {
+				bestSet, best = s, r
+			}
+		}
+	}
+	return bestSet, best
+}
+
+// dashSegments liste les segments de la représentation r : SegmentTemplate
+// (avec ou sans SegmentTimeline), SegmentList, ou fichier unique (BaseURL).
+func dashSegments(base string, set *mpdAdaptationSet, r *mpdRepresentation, duration float64) ([]segment, error) {
+	base, err := resolveAll(base, set.BaseURL, r.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	tpl := r.SegmentTemplate
+	if tpl == nil {
+		tpl = set.SegmentTemplate
+	}
+	list := r.SegmentList
+	if list == nil {
+		list = set.SegmentList
+	}
+
+	var segs []segment
+	add := func(ref string, init bool) error {
+		u, err := resolve(base, ref)
+		segs = append(segs, segment{url: u, init: init})
+		return err
+	}
+	switch {
+	case tpl != nil:
+		if tpl.Initialization != "" {
+			if err := add(expandTemplate(tpl.Initialization, r, 0, 0), true); err != nil {
+				return nil, err
+			}
+		}
+		number := int64(1)
+		if tpl.StartNumber != nil {
+			number = *tpl.StartNumber
+		}
+		if len(tpl.Timeline) > 0 {
+			var t int64
+			end := int64(duration * float64(max(tpl.Timescale, 1)))
+			for i, s := range tpl.Timeline {
+				if s.T != nil {
+					t = *s.T
+				}
+				repeat := s.R
+				if repeat < 0 {
+					// r=-1 : jusqu'au prochain S ou à la fin de la période.
+					stop := end
+					if i+1 < len(tpl.Timeline) && tpl.Timeline[i+1].T != nil {
+						stop = *tpl.Timeline[i+1].T
+					}
+					repeat = max((stop-t+s.D-1)/max(s.D, 1)-1, 0)
+				}
+				for k := int64(0); k <= repeat; k++ {
+					if err := add(expandTemplate(tpl.Media, r, number, t), false); err != nil {
+						return nil, err
+					}
+					number++
+					t += s.D
+				}
+			}
+			break
+		}
+		if tpl.Duration <= 0 || duration <= 0 {
+			return nil, fmt.Errorf("%w: DASH template without duration", ErrUnsupported)
+		}
+		n := int64(math.Ceil(duration * float64(max(tpl.Timescale, 1)) / float64(tpl.Duration)))
+		for k := int64(0); k < n; k++ {
+			if err := add(expandTemplate(tpl.Media, r, number+k, k*tpl.Duration), false); err != nil {
+				return nil, err
+			}
+		}
+	case list != nil:
+		if list.Initialization != nil && list.Initialization.SourceURL != "" {
+			if err := add(list.Initialization.SourceURL, true); err != nil {
+				return nil, err
+			}
+		}
+		for _, s := range list.SegmentURLs {
+			if err := add(s.Media, false); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		// SegmentBase ou rien : toute la représentation est un seul fichier.
+		segs = append(segs, segment{url: base})
+	}
+	return segs, nil
+}
+
+var reTemplateVar = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(?:%0(\d+)d)?\$`)
+
+// expandTemplate remplace les variables $Number$, $Time$... d'un modèle
+// d'URL DASH.
+func expandTemplate(s string, r *mpdRepresentation, number, t int64) string {
+	s = reTemplateVar.ReplaceAllStringFunc(s, func(v string) string {
+		m := reTemplateVar.FindStringSubmatch(v)
+		var n int64
+		switch m[1] {
+		case "RepresentationID":
+			return r.ID
+		case "Number":
+			n = number
+		case "Time":
+			n = t
+		case "Bandwidth":
+			n = int64(r.Bandwidth)
+		}
+		if m[2] != "" {
+			w, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", w, n)
+		}
+		return strconv.FormatInt(n, 10)
+	})
+	return strings.ReplaceAll(s, "$$", "$")
+}
+
+// resolveAll résout successivement les BaseURL refs ("" ignoré) à partir de
+// base.
+func resolveAll(base string, refs ...string) (string, error) {
+	for _, ref := range refs {
+		if strings.TrimSpace(ref) == "" {
+			continue
+		}
+		var err error
+		if base, err = resolve(base, ref); err != nil {
+			return "", err
+		}
+	}
+	return base, nil
+}
+
+var reISODuration = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration convertit une durée ISO 8601 ("PT1H2M3.5S") en secondes ;
+// 0 si elle est illisible.
+func parseISODuration(s string) float64 {
+	m := reISODuration.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0
+	}
+	var total float64
+	for i, unit := range []float64{86400, 3600, 60, 1} {
+		if v, err := strconv.ParseFloat(m[i+1], 64); err == nil {
+			total += v * unit
+		}
+	}
+	return total
+}
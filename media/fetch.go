@@ -0,0 +1,346 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+// Fetch => fichier direct, repris par requête Range
+// -----------------------------------------------------------------------------
+
+// maxAttempts est le nombre de tentatives par requête ; un fichier reprend
+// là où la tentative précédente s'est arrêtée.
+const maxAttempts = 3
+
+// Fetch télécharge u dans path. Le fichier est écrit dans path.part puis
+// renommé ; un path.part laissé par un run interrompu est repris (requête
+// Range). Si le serveur ignore Range, le téléchargement recommence au début.
+// progress peut être nil.
+func Fetch(ctx context.Context, client *http.Client, u, path string, progress Progress) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = fetchOnce(ctx, client, u, path, progress); err == nil || ctx.Err() != nil {
+			return err
+		}
+		if attempt < maxAttempts {
+			if werr := backoff(ctx, attempt); werr != nil {
+				return werr
+			}
+		}
+	}
+	return err
+}
+
+// backoff attend attempt secondes avant une nouvelle tentative, ou moins si
+// ctx est annulé (son erreur est alors retournée).
+func backoff(ctx context.Context, attempt int) error {
+	t := time.NewTimer(time.Duration(attempt) * time.Second)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func fetchOnce(ctx context.Context, client *http.Client, u, path string, progress Progress) error {
+	part := path + ".part"
+	var offset int64
+	if fi, err := os.Stat(part); err == nil {
+		offset = fi.Size()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 &&
+		strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)):
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// Pas de .part, ou Range ignoré : on repart de zéro.
+		flags |= os.O_TRUNC
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// path.part n'est complet que s'il a la taille annoncée par
+		// "Content-Range: bytes */N" ; sinon on recommence au début.
+		if size, ok := completeLength(resp.Header.Get("Content-Range")); ok && size == offset {
+			return os.Rename(part, path)
+		}
+		os.Remove(part)
+		return fmt.Errorf("GET %s: %s for %d bytes (Content-Range %q), restarting", u, resp.Status, offset, resp.Header.Get("Content-Range"))
+	case resp.StatusCode == http.StatusPartialContent:
+		// Content-Range ne correspond pas à path.part : la tentative
+		// suivante recommence au début.
+		os.Remove(part)
+		return fmt.Errorf("GET %s: unexpected Content-Range %q, restarting", u, resp.Header.Get("Content-Range"))
+	default:
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	var total int64
+	if resp.ContentLength > 0 {
+		total = offset + resp.ContentLength
+	}
+	w := &progressWriter{w: f, done: offset, total: total, progress: progress}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(part, path)
+}
+
+// completeLength retourne N d'un en-tête "Content-Range: bytes */N".
+func completeLength(contentRange string) (int64, bool) {
+	n, ok := strings.CutPrefix(contentRange, "bytes */")
+	if !ok {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(n, 10, 64)
+	return size, err == nil
+}
+
+// progressWriter signale à progress les octets écrits dans w.
+type progressWriter struct {
+	w           io.Writer
+	done, total int64
+	progress    Progress
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.progress.report(p.done, p.total)
+	return n, err
+}
+
+// get retourne le corps de u ; rng est un en-tête Range ("" : tout).
+func get(ctx context.Context, client *http.Client, u, rng string) ([]byte, error) {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var b []byte
+		if b, err = getOnce(ctx, client, u, rng); err == nil || ctx.Err() != nil {
+			return b, err
+		}
+		if attempt < maxAttempts {
+			if werr := backoff(ctx, attempt); werr != nil {
+				return nil, werr
+			}
+		}
+	}
+	return nil, err
+}
+
+func getOnce(ctx context.Context, client *http.Client, u, rng string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case rng != "" && resp.StatusCode == http.StatusOK:
+		// Le serveur ignore Range : le fichier entier n'est pas le segment.
+		return nil, fmt.Errorf("GET %s: server ignored Range %s", u, rng)
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent:
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// -----------------------------------------------------------------------------
+// Segments => un fichier par segment dans <path>.<clé>.parts, puis concaténation
+// -----------------------------------------------------------------------------
+
+// segment est un morceau d'une piste HLS ou DASH.
+type segment struct {
+	url string
+	// rng est l'en-tête Range du segment ("" : tout le fichier).
+	rng string
+	// decrypt déchiffre le segment (HLS AES-128) ; nil si en clair.
+	decrypt func([]byte) ([]byte, error)
+	// init marque le segment d'initialisation d'une piste fMP4.
+	init bool
+}
+
+// segmentWorkers est le nombre de segments téléchargés en parallèle.
+const segmentWorkers = 4
+
+// downloadSegments écrit les segments segs (init en tête, s'il y en a) dans
+// out. Chaque segment est d'abord enregistré dans out.<clé>.parts/ (voir
+// partsDir) : ceux déjà présents après une interruption ne sont pas
+// retéléchargés. Le dossier est supprimé une fois out écrit.
+func downloadSegments(ctx context.Context, client *http.Client, segs []segment, out string, progress Progress) error {
+	dir := partsDir(out, segs)
+	removeStaleParts(out, dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := func(i int) string { return filepath.Join(dir, fmt.Sprintf("%05d.seg", i)) }
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		mu       sync.Mutex
+		done     int64
+	)
+	saved := func() {
+		mu.Lock()
+		done++
+		progress.report(done, int64(len(segs)))
+		mu.Unlock()
+	}
+	for w := 0; w < segmentWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := saveSegment(ctx, client, segs[i], name(i)); err != nil {
+					errOnce.Do(func() { firstErr = err; cancel() })
+					continue
+				}
+				saved()
+			}
+		}()
+	}
+	for i := range segs {
+		if _, err := os.Stat(name(i)); err == nil {
+			saved()
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	part := out + ".part"
+	f, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	for i := range segs {
+		in, err := os.Open(name(i))
+		if err != nil {
+			f.Close()
+			return err
+		}
+		_, err = io.Copy(f, in)
+		in.Close()
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(part, out); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// partsDir retourne le dossier des segments de out. Il est nommé d'après un
+// hash des URL (sans query, souvent un jeton qui change d'un run à l'autre)
+// et des plages des segments : les segments d'une autre playlist ne sont
+// jamais repris.
+func partsDir(out string, segs []segment) string {
+	h := sha256.New()
+	for _, s := range segs {
+		u := s.url
+		if i := strings.IndexByte(u, '?'); i >= 0 {
+			u = u[:i]
+		}
+		fmt.Fprintf(h, "%s\n%s\n", u, s.rng)
+	}
+	return fmt.Sprintf("%s.%x.parts", out, h.Sum(nil)[:6])
+}
+
+// removeStaleParts supprime les dossiers de segments de out laissés par une
+// autre playlist, autres que keep.
+func removeStaleParts(out, keep string) {
+	entries, err := os.ReadDir(filepath.Dir(out))
+	if err != nil {
+		return
+	}
+	prefix := filepath.Base(out) + "."
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".parts") {
+			continue
+		}
+		// Seulement out.<clé>.parts, pas out.audio.<clé>.parts.
+		if key := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".parts"); strings.Contains(key, ".") {
+			continue
+		}
+		if p := filepath.Join(filepath.Dir(out), name); p != keep {
+			os.RemoveAll(p)
+		}
+	}
+}
+
+// saveSegment télécharge s dans path (via un fichier temporaire : un segment
+// présent est toujours complet).
+func saveSegment(ctx context.Context, client *http.Client, s segment, path string) error {
+	b, err := get(ctx, client, s.url, s.rng)
+	if err != nil {
+		return err
+	}
+	if s.decrypt != nil {
+		if b, err = s.decrypt(b); err != nil {
+			return fmt.Errorf("segment %s: %w", s.url, err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,352 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+// HLS => master => meilleure variante => segments (AES-128)
+// -----------------------------------------------------------------------------
+
+// hlsVariant est une entrée #EXT-X-STREAM-INF d'une playlist master.
+type hlsVariant struct {
+	uri       string
+	bandwidth int
+	// audio est le groupe #EXT-X-MEDIA des pistes audio séparées.
+	audio string
+}
+
+// hlsRendition est une entrée #EXT-X-MEDIA (piste audio séparée).
+type hlsRendition struct {
+	typ, group, uri string
+	def             bool
+}
+
+// hlsPlaylist est une playlist lue : master (variants, renditions) ou média
+// (segments).
+type hlsPlaylist struct {
+	variants   []hlsVariant
+	renditions []hlsRendition
+	segments   []segment
+	// live est vrai sans #EXT-X-ENDLIST : playlist d'un direct.
+	live bool
+}
+
+// downloadHLS télécharge la playlist u (master ou média) dans path. Les
+// segments MPEG-TS sont concaténés puis remuxés en MP4 par ffmpeg s'il est
+// disponible ; sinon le fichier reste en .ts (voir Download).
+func downloadHLS(ctx context.Context, client *http.Client, u, path string, progress Progress) (string, error) {
+	pl, err := loadHLS(ctx, client, u)
+	if err != nil {
+		return "", err
+	}
+	audio := ""
+	if len(pl.variants) > 0 {
+		v := bestVariant(pl.variants)
+		if a := audioRendition(pl.renditions, v.audio); a != "" {
+			if audio, err = resolve(u, a); err != nil {
+				return "", err
+			}
+		}
+		if u, err = resolve(u, v.uri); err != nil {
+			return "", err
+		}
+		if pl, err = loadHLS(ctx, client, u); err != nil {
+			return "", err
+		}
+	}
+	if pl.live {
+		return "", fmt.Errorf("%w: live HLS stream", ErrUnsupported)
+	}
+	if len(pl.segments) == 0 {
+		return "", fmt.Errorf("no segment in %s", u)
+	}
+	if audio != "" && !hasFFmpeg() {
+		return "", fmt.Errorf("HLS with separate audio: %w", errNoFFmpeg)
+	}
+
+	track := path + ".video"
+	if err := downloadSegments(ctx, client, pl.segments, track, progress); err != nil {
+		return "", err
+	}
+	inputs := []string{track}
+	if audio != "" {
+		apl, err := loadHLS(ctx, client, audio)
+		if err != nil {
+			return "", err
+		}
+		if err := downloadSegments(ctx, client, apl.segments, path+".audio", progress); err != nil {
+			return "", err
+		}
+		inputs = append(inputs, path+".audio")
+	}
+
+	fmp4 := pl.segments[0].init
+	switch {
+	case len(inputs) > 1 || (!fmp4 && hasFFmpeg()):
+		if err := ffmpegCopy(ctx, path, inputs...); err != nil {
+			return "", err
+		}
+	case fmp4:
+		// init + segments fMP4 forment déjà un MP4 fragmenté lisible.
+		return path, os.Rename(track, path)
+	default:
+		ts := tsPath(path)
+		return ts, os.Rename(track, ts)
+	}
+	for _, in := range inputs {
+		os.Remove(in)
+	}
+	return path, nil
+}
+
+// bestVariant retourne la variante de plus haut débit.
+func bestVariant(vs []hlsVariant) hlsVariant {
+	best := vs[0]
+	for _, v := range vs[1:] {
+		if v.bandwidth > best.bandwidth {
+			best = v
+		}
+	}
+	return best
+}
+
+// audioRendition retourne l'URI de la piste audio du groupe group (la piste
+// par défaut si le groupe en a plusieurs) ; "" si l'audio est dans la vidéo.
+func audioRendition(rs []hlsRendition, group string) string {
+	uri := ""
+	for _, r := range rs {
+		if r.typ != "AUDIO" || r.group != group || r.uri == "" {
+			continue
+		}
+		if uri == "" || r.def {
+			uri = r.uri
+		}
+	}
+	return uri
+}
+
+// loadHLS lit la playlist u. Les URI des segments sont résolues ; le segment
+// d'initialisation (#EXT-X-MAP), s'il existe, est le premier.
+func loadHLS(ctx context.Context, client *http.Client, u string) (*hlsPlaylist, error) {
+	b, err := get(ctx, client, u, "")
+	if err != nil {
+		return nil, err
+	}
+	return parseHLS(ctx, client, u, b)
+}
+
+func parseHLS(ctx context.Context, client *http.Client, u string, b []byte) (*hlsPlaylist, error) {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	if !sc.Scan() || !strings.HasPrefix(strings.TrimSpace(sc.Text()), "#EXTM3U") {
+		return nil, fmt.Errorf("%s is not an HLS playlist", u)
+	}
+
+	pl := &hlsPlaylist{live: true}
+	keys := &hlsKeys{client: client, keys: map[string][]byte{}}
+	var (
+		pending *hlsVariant
+		key     map[string]string
+		seq     int64
+		rng     string
+		// next est le début du prochain #EXT-X-BYTERANGE sans @offset.
+		next    int64
+		lastURI string
+	)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		tag, value, _ := strings.Cut(line, ":")
+		switch {
+		case line == "":
+		case tag == "#EXT-X-STREAM-INF":
+			a := parseAttrs(value)
+			bw, _ := strconv.Atoi(a["BANDWIDTH"])
+			pending = &hlsVariant{bandwidth: bw, audio: a["AUDIO"]}
+		case tag == "#EXT-X-MEDIA":
+			a := parseAttrs(value)
+			pl.renditions = append(pl.renditions, hlsRendition{typ: a["TYPE"], group: a["GROUP-ID"], uri: a["URI"], def: a["DEFAULT"] == "YES"})
+		case tag == "#EXT-X-MEDIA-SEQUENCE":
+			seq, _ = strconv.ParseInt(value, 10, 64)
+		case tag == "#EXT-X-KEY":
+			key = parseAttrs(value)
+			if m := key["METHOD"]; m != "NONE" && m != "AES-128" {
+				return nil, fmt.Errorf("%w: HLS encryption %s", ErrUnsupported, m)
+			}
+		case tag == "#EXT-X-MAP":
+			a := parseAttrs(value)
+			s, err := hlsSegment(u, a["URI"], byteRangeHeader(a["BYTERANGE"], nil))
+			if err != nil {
+				return nil, err
+			}
+			s.init = true
+			pl.segments = append(pl.segments, s)
+		case tag == "#EXT-X-BYTERANGE":
+			rng = value
+		case tag == "#EXT-X-ENDLIST":
+			pl.live = false
+		case strings.HasPrefix(line, "#"):
+		case pending != nil:
+			pending.uri = line
+			pl.variants = append(pl.variants, *pending)
+			pending = nil
+		default:
+			if line != lastURI {
+				next = 0
+			}
+			lastURI = line
+			s, err := hlsSegment(u, line, byteRangeHeader(rng, &next))
+			if err != nil {
+				return nil, err
+			}
+			rng = ""
+			if key != nil && key["METHOD"] == "AES-128" {
+				s.decrypt = keys.decrypter(ctx, u, key, seq)
+			}
+			pl.segments = append(pl.segments, s)
+			seq++
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(pl.variants) > 0 {
+		pl.live = false
+	}
+	return pl, nil
+}
+
+func hlsSegment(base, uri, rng string) (segment, error) {
+	u, err := resolve(base, uri)
+	return segment{url: u, rng: rng}, err
+}
+
+// byteRangeHeader convertit "n[@o]" en en-tête Range. Sans @o, le morceau
+// suit le précédent du même fichier (*next).
+func byteRangeHeader(v string, next *int64) string {
+	if v == "" {
+		return ""
+	}
+	ns, off, hasOffset := strings.Cut(v, "@")
+	n, _ := strconv.ParseInt(ns, 10, 64)
+	var start int64
+	if hasOffset {
+		start, _ = strconv.ParseInt(off, 10, 64)
+	} else if next != nil {
+		start = *next
+	}
+	if next != nil {
+		*next = start + n
+	}
+	return fmt.Sprintf("bytes=%d-%d", start, start+n-1)
+}
+
+// parseAttrs lit une liste d'attributs HLS : A=1,B="x,y",C=z.
+func parseAttrs(s string) map[string]string {
+	out := map[string]string{}
+	for s != "" {
+		k, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		var v string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			v = rest[1 : end+1]
+			rest = rest[min(end+2, len(rest)):]
+		} else {
+			v, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		out[strings.TrimSpace(k)] = v
+		s = strings.TrimPrefix(rest, ",")
+	}
+	return out
+}
+
+// -----------------------------------------------------------------------------
+// AES-128 => clés téléchargées une seule fois
+// -----------------------------------------------------------------------------
+
+type hlsKeys struct {
+	client *http.Client
+	mu     sync.Mutex
+	keys   map[string][]byte
+}
+
+// decrypter retourne le déchiffrement d'un segment de numéro seq avec la clé
+// #EXT-X-KEY attrs (IV explicite, sinon le numéro de séquence).
+func (k *hlsKeys) decrypter(ctx context.Context, base string, attrs map[string]string, seq int64) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		keyURL, err := resolve(base, attrs["URI"])
+		if err != nil {
+			return nil, err
+		}
+		key, err := k.get(ctx, keyURL)
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, aes.BlockSize)
+		if v := attrs["IV"]; v != "" {
+			h, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(v, "0x"), "0X"))
+			if err != nil || len(h) != aes.BlockSize {
+				return nil, fmt.Errorf("invalid IV %q", v)
+			}
+			iv = h
+		} else {
+			binary.BigEndian.PutUint64(iv[8:], uint64(seq))
+		}
+		return decryptAES128(key, iv, data)
+	}
+}
+
+func (k *hlsKeys) get(ctx context.Context, u string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[u]; ok {
+		return key, nil
+	}
+	key, err := get(ctx, k.client, u, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("HLS key %s: %d bytes, want 16", u, len(key))
+	}
+	k.keys[u] = key
+	return key, nil
+}
+
+// decryptAES128 déchiffre data (AES-128-CBC, bourrage PKCS#7).
+func decryptAES128(key, iv, data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("encrypted segment is not a multiple of the block size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize || pad > len(out) {
+		return nil, errors.New("invalid PKCS#7 padding")
+	}
+	return out[:len(out)-pad], nil
+}
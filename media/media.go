@@ -0,0 +1,146 @@
+// Package media télécharge des vidéos sans yt-dlp : fichiers directs (reprise
+// par requêtes Range), playlists HLS (master / variantes, segments AES-128)
+// et manifests DASH. Les segments sont écrits au fur et à mesure pour
+// reprendre un téléchargement interrompu, puis assemblés ; ffmpeg, s'il est
+// installé, sert seulement à remuxer (.ts => .mp4) ou à fusionner les pistes
+// audio et vidéo séparées d'un manifest DASH.
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Kind est le type de source d'une URL.
+type Kind int
+
+const (
+	// Unknown : page web ou lien de plateforme, à confier à yt-dlp.
+	Unknown Kind = iota
+	// File : fichier vidéo direct (.mp4, .webm...).
+	File
+	// HLS : playlist .m3u8.
+	HLS
+	// DASH : manifest .mpd.
+	DASH
+)
+
+var fileExts = map[string]bool{".mp4": true, ".m4v": true, ".mov": true, ".webm": true, ".mkv": true, ".ts": true}
+
+// KindOf retourne le type de source de u d'après l'extension de son chemin.
+func KindOf(u string) Kind {
+	p, err := url.Parse(u)
+	if err != nil {
+		return Unknown
+	}
+	ext := strings.ToLower(filepath.Ext(p.Path))
+	switch {
+	case ext == ".m3u8":
+		return HLS
+	case ext == ".mpd":
+		return DASH
+	case fileExts[ext]:
+		return File
+	}
+	return Unknown
+}
+
+// Progress reçoit l'avancement d'un téléchargement : done sur total octets
+// pour un fichier, segments pour HLS / DASH ; total vaut 0 s'il est inconnu.
+type Progress func(done, total int64)
+
+func (p Progress) report(done, total int64) {
+	if p != nil {
+		p(done, total)
+	}
+}
+
+// ErrUnsupported signale une source que Download ne sait pas traiter (type
+// inconnu, flux en direct, chiffrement autre qu'AES-128...).
+var ErrUnsupported = errors.New("unsupported media source")
+
+// Download télécharge la vidéo u dans path avec client et retourne le fichier
+// écrit : path, ou path avec l'extension .ts pour une playlist HLS en MPEG-TS
+// quand ffmpeg n'est pas disponible pour la remuxer. Un fichier déjà présent
+// et non vide n'est pas retéléchargé ; un téléchargement interrompu reprend
+// là où il s'était arrêté. progress peut être nil.
+func Download(ctx context.Context, client *http.Client, u, path string, progress Progress) (string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for _, p := range []string{path, tsPath(path)} {
+		if fileExistsAndNonZero(p) {
+			return p, nil
+		}
+	}
+	switch KindOf(u) {
+	case File:
+		return path, Fetch(ctx, client, u, path, progress)
+	case HLS:
+		return downloadHLS(ctx, client, u, path, progress)
+	case DASH:
+		return path, downloadDASH(ctx, client, u, path, progress)
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupported, u)
+}
+
+// tsPath retourne path avec l'extension .ts.
+func tsPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".ts"
+}
+
+// resolve retourne ref relatif à base.
+func resolve(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+func fileExistsAndNonZero(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir() && fi.Size() > 0
+}
+
+// -----------------------------------------------------------------------------
+// ffmpeg => remux / fusion des pistes (optionnel)
+// -----------------------------------------------------------------------------
+
+// errNoFFmpeg est retourné quand une opération exige ffmpeg.
+var errNoFFmpeg = errors.New("ffmpeg not found in PATH")
+
+func hasFFmpeg() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+// ffmpegCopy réunit les pistes de inputs dans out sans réencodage.
+func ffmpegCopy(ctx context.Context, out string, inputs ...string) error {
+	if !hasFFmpeg() {
+		return errNoFFmpeg
+	}
+	args := []string{"-y", "-loglevel", "error"}
+	for _, in := range inputs {
+		args = append(args, "-i", in)
+	}
+	for i := range inputs {
+		args = append(args, "-map", fmt.Sprintf("%d", i))
+	}
+	args = append(args, "-c", "copy", "-movflags", "+faststart", out)
+	if b, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
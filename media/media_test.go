@@ -0,0 +1,273 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// server sert les fichiers de files et compte les requêtes par chemin.
+type server struct {
+	*httptest.Server
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func newServer(t *testing.T, files map[string]string) *server {
+	s := &server{hits: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.mu.Unlock()
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, strings.NewReader(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func encryptAES128(key, iv []byte, data string) string {
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	plain := append([]byte(data), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+	return string(out)
+}
+
+func TestKindOf(t *testing.T) {
+	for u, want := range map[string]Kind{
+		"https://cdn.example/v/master.m3u8?token=x": HLS,
+		"https://cdn.example/manifest.MPD":          DASH,
+		"https://cdn.example/video.mp4#t=10":        File,
+		"https://www.loom.com/share/abc":            Unknown,
+	} {
+		if got := KindOf(u); got != want {
+			t.Errorf("KindOf(%q) = %v, want %v", u, got, want)
+		}
+	}
+}
+
+func TestDownloadHLSMasterAES(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	iv := make([]byte, 16)
+	iv[15] = 7 // IV implicite : numéro de séquence du premier segment.
+	explicitIV := bytes.Repeat([]byte{0xab}, 16)
+	s := newServer(t, map[string]string{
+		"/master.m3u8": "#EXTM3U\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=800000,CODECS=\"avc1,mp4a\"\nlow/index.m3u8\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=2400000,RESOLUTION=1280x720\nhigh/index.m3u8\n",
+		"/high/index.m3u8": "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:7\n" +
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"/key.bin\"\n#EXTINF:4,\nseg7.ts\n" +
+			fmt.Sprintf("#EXT-X-KEY:METHOD=AES-128,URI=\"/key.bin\",IV=0x%x\n#EXTINF:4,\nseg8.ts\n", explicitIV) +
+			"#EXT-X-KEY:METHOD=NONE\n#EXTINF:4,\nseg9.ts\n#EXT-X-ENDLIST\n",
+		"/key.bin":        string(key),
+		"/high/seg7.ts":   encryptAES128(key, iv, "first segment;"),
+		"/high/seg8.ts":   encryptAES128(key, explicitIV, "second segment;"),
+		"/high/seg9.ts":   "clear segment",
+		"/low/index.m3u8": "#EXTM3U\n#EXTINF:4,\nlow.ts\n#EXT-X-ENDLIST\n",
+	})
+	if hasFFmpeg() {
+		t.Skip("ffmpeg would remux the fake MPEG-TS segments")
+	}
+	path := filepath.Join(t.TempDir(), "video-01.mp4")
+	fn, err := Download(context.Background(), s.Client(), s.URL+"/master.m3u8", path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn != tsPath(path) {
+		t.Errorf("Download wrote %q, want %q", fn, tsPath(path))
+	}
+	got, _ := os.ReadFile(fn)
+	if want := "first segment;second segment;clear segment"; string(got) != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if s.hits["/low/index.m3u8"] != 0 || s.hits["/key.bin"] != 1 {
+		t.Errorf("hits = %v, want best variant only and one key fetch", s.hits)
+	}
+	if left, _ := filepath.Glob(path + ".video.*.parts"); len(left) > 0 {
+		t.Errorf("segments folder left behind: %q", left)
+	}
+}
+
+func TestDownloadHLSByteRangeResume(t *testing.T) {
+	s := newServer(t, map[string]string{
+		"/v.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"all.mp4\",BYTERANGE=\"4@0\"\n" +
+			"#EXTINF:4,\n#EXT-X-BYTERANGE:3@4\nall.mp4\n" +
+			"#EXTINF:4,\n#EXT-X-BYTERANGE:5\nall.mp4\n#EXT-X-ENDLIST\n",
+		"/all.mp4": "initAAABBBBB",
+	})
+	path := filepath.Join(t.TempDir(), "video-01.mp4")
+	pl, err := loadHLS(context.Background(), s.Client(), s.URL+"/v.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Run interrompu : le premier segment est déjà là. Le dossier d'une autre
+	// playlist, lui, n'est pas repris et disparaît.
+	parts := partsDir(path+".video", pl.segments)
+	os.MkdirAll(parts, 0o755)
+	os.WriteFile(filepath.Join(parts, "00001.seg"), []byte("aaa"), 0o644)
+	stale := partsDir(path+".video", pl.segments[:1])
+	os.MkdirAll(stale, 0o755)
+	os.WriteFile(filepath.Join(stale, "00002.seg"), []byte("stale"), 0o644)
+
+	fn, err := Download(context.Background(), s.Client(), s.URL+"/v.m3u8", path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(fn)
+	if fn != path || string(got) != "initaaaBBBBB" {
+		t.Errorf("Download wrote %q = %q, want %q = initaaaBBBBB", fn, got, path)
+	}
+	if s.hits["/all.mp4"] != 2 {
+		t.Errorf("all.mp4 fetched %d times, want 2 (init + missing segment)", s.hits["/all.mp4"])
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale segments folder left behind")
+	}
+}
+
+func TestPartsDir(t *testing.T) {
+	segs := []segment{{url: "https://cdn.example/v/seg1.ts?token=a"}, {url: "https://cdn.example/v/seg2.ts?token=a"}}
+	retoken := []segment{{url: "https://cdn.example/v/seg1.ts?token=b"}, {url: "https://cdn.example/v/seg2.ts?token=b"}}
+	other := []segment{{url: "https://cdn.example/w/seg1.ts"}, {url: "https://cdn.example/w/seg2.ts"}}
+	ranged := []segment{{url: "https://cdn.example/v/seg1.ts", rng: "bytes=0-9"}, {url: "https://cdn.example/v/seg2.ts"}}
+	d := partsDir("out", segs)
+	if !strings.HasPrefix(d, "out.") || !strings.HasSuffix(d, ".parts") {
+		t.Errorf("partsDir = %q, want out.<key>.parts", d)
+	}
+	if partsDir("out", retoken) != d {
+		t.Error("partsDir depends on the query string")
+	}
+	if partsDir("out", other) == d || partsDir("out", ranged) == d || partsDir("out", segs[:1]) == d {
+		t.Error("partsDir is shared by different playlists")
+	}
+}
+
+func TestDownloadDASHTemplate(t *testing.T) {
+	files := map[string]string{
+		"/dash/manifest.mpd": `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/seg-$Number%03d$.m4s" startNumber="1" timescale="1000">
+        <SegmentTimeline><S t="0" d="4000" r="1"/><S d="2000"/></SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="v360" bandwidth="500000"/>
+      <Representation id="v720" bandwidth="1500000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`,
+	}
+	for _, p := range []string{"init.mp4", "seg-001.m4s", "seg-002.m4s", "seg-003.m4s"} {
+		files["/dash/v720/"+p] = "[" + p + "]"
+	}
+	s := newServer(t, files)
+	path := filepath.Join(t.TempDir(), "video-01.mp4")
+	fn, err := Download(context.Background(), s.Client(), s.URL+"/dash/manifest.mpd", path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(fn)
+	if want := "[init.mp4][seg-001.m4s][seg-002.m4s][seg-003.m4s]"; string(got) != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	for s, want := range map[string]float64{"PT1H2M3.5S": 3723.5, "PT10S": 10, "P1DT1S": 86401, "bogus": 0} {
+		if got := parseISODuration(s); got != want {
+			t.Errorf("parseISODuration(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestFetchRestartsOnBadContentRange(t *testing.T) {
+	body := "0123456789"
+	var ranges []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			// Mauvaise plage : le .part ne peut pas être complété.
+			w.Header().Set("Content-Range", "bytes 0-9/10")
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, body)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "v.mp4")
+	os.WriteFile(path+".part", []byte("XXXX"), 0o644)
+
+	if err := Fetch(context.Background(), s.Client(), s.URL+"/v.mp4", path, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != body || len(ranges) != 2 || ranges[1] != "" {
+		t.Errorf("content = %q, ranges = %q; want %q after a restart without Range", got, ranges, body)
+	}
+}
+
+func TestFetchRangeNotSatisfiable(t *testing.T) {
+	body := "0123456789"
+	for _, tt := range []struct {
+		name, part, contentRange string
+		wantRestart              bool
+	}{
+		{"complete part", body, "bytes */10", false},
+		{"shorter part", "0123", "bytes */3", true},
+		{"longer part", body + "XX", "bytes */10", true},
+		{"no Content-Range", body, "", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				if r.Header.Get("Range") != "" {
+					if tt.contentRange != "" {
+						w.Header().Set("Content-Range", tt.contentRange)
+					}
+					w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+					return
+				}
+				fmt.Fprint(w, body)
+			}))
+			defer s.Close()
+			path := filepath.Join(t.TempDir(), "v.mp4")
+			os.WriteFile(path+".part", []byte(tt.part), 0o644)
+
+			if err := Fetch(context.Background(), s.Client(), s.URL+"/v.mp4", path, nil); err != nil {
+				t.Fatal(err)
+			}
+			got, _ := os.ReadFile(path)
+			if string(got) != body || (len(ranges) == 2) != tt.wantRestart {
+				t.Errorf("content = %q, ranges = %q, want %q (restart: %v)", got, ranges, body, tt.wantRestart)
+			}
+		})
+	}
+}
+
+func TestSegmentRangeIgnored(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "whole file")
+	}))
+	defer s.Close()
+	if _, err := getOnce(context.Background(), s.Client(), s.URL, "bytes=0-3"); err == nil {
+		t.Error("getOnce accepted a 200 reply to a Range request")
+	}
+}
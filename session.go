@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"skool-video-dl/skool"
+)
+
+// -----------------------------------------------------------------------------
+// session => cookies réutilisés ou login, puis fetcher chrome / http
+// -----------------------------------------------------------------------------
+
+// session est la connexion Skool ouverte pour toute la durée du run.
+type session struct {
+	fetcher skool.PageFetcher
+	// cookies contient tous les cookies connus, transmis aussi au downloader.
+	cookies []*http.Cookie
+	cleanup []func()
+	// reused indique une session reprise de cookies (-cookies,
+	// -session-file, -auth-token) plutôt qu'ouverte par login.
+	reused bool
+	// browser est le navigateur du fetcher chrome, nil en mode http.
+	browser context.Context
+}
+
+func (s *session) close() {
+	for i := len(s.cleanup) - 1; i >= 0; i-- {
+		s.cleanup[i]()
+	}
+}
+
+// openSession ouvre une session authentifiée. Les cookies fournis (-cookies,
+// -session-file, -auth-token) sont utilisés en priorité ; sinon on se connecte
+// avec -email/-password et la session obtenue est sauvée dans -session-file.
+// En mode http, Chrome n'est lancé que le temps de ce login.
+func openSession(cfg Config) (*session, error) {
+	cookies, err := initialCookies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	loggedIn := skool.HasAuthCookie(skool.SkoolCookies(cookies))
+	s := &session{cookies: cookies, reused: loggedIn}
+	timeout := time.Duration(cfg.Wait) * time.Second
+
+	if cfg.Fetcher == "http" {
+		if !loggedIn {
+			bctx, cancel := skool.SetupBrowser(cfg.Headless)
+			err := s.login(bctx, cfg)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+		}
+		s.fetcher = skool.NewHTTPFetcher(skool.SkoolCookies(s.cookies))
+		return s, nil
+	}
+
+	bctx, cancel := skool.SetupBrowser(cfg.Headless)
+	s.cleanup = append(s.cleanup, cancel)
+	s.browser = bctx
+	if loggedIn {
+		err = skool.SetBrowserCookies(bctx, skool.SkoolCookies(cookies))
+	} else {
+		err = s.login(bctx, cfg)
+	}
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.fetcher = skool.NewChromeFetcher(bctx, timeout, cfg.Concurrency)
+	return s, nil
+}
+
+// canRelogin indique si une session reprise de cookies peut être rouverte par
+// login (-email et -password donnés), quand Skool la refuse.
+func (s *session) canRelogin(cfg Config) bool {
+	return s.reused && cfg.Email != "" && cfg.Password != ""
+}
+
+// relogin se connecte avec -email/-password à la place de cookies expirés :
+// la nouvelle session remplace l'ancienne dans -session-file et dans le
+// fetcher (le navigateur en mode chrome, un nouveau fetcher en mode http).
+func (s *session) relogin(cfg Config) error {
+	s.reused = false
+	if s.browser != nil {
+		return s.login(s.browser, cfg)
+	}
+	bctx, cancel := skool.SetupBrowser(cfg.Headless)
+	err := s.login(bctx, cfg)
+	cancel()
+	if err != nil {
+		return err
+	}
+	s.fetcher = skool.NewHTTPFetcher(skool.SkoolCookies(s.cookies))
+	return nil
+}
+
+// initialCookies charge les cookies de -cookies (ou à défaut -session-file) et
+// ajoute -auth-token.
+func initialCookies(cfg Config) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	switch {
+	case cfg.CookiesFile != "":
+		cks, err := skool.LoadCookies(cfg.CookiesFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load -cookies: %w", err)
+		}
+		cookies = cks
+	case fileExists(cfg.SessionFile):
+		cks, err := skool.LoadCookies(cfg.SessionFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load -session-file: %w", err)
+		}
+		cookies = cks
+	}
+	if cfg.AuthToken != "" {
+		cookies = append(cookies, &http.Cookie{Name: skool.AuthCookie, Value: cfg.AuthToken, Domain: ".skool.com", Path: "/"})
+	}
+	return cookies, nil
+}
+
+// login se connecte dans le navigateur bctx, récupère les cookies de session
+// et les sauve dans -session-file.
+func (s *session) login(bctx context.Context, cfg Config) error {
+	if cfg.Email == "" || cfg.Password == "" {
+		return fmt.Errorf("session expired and no -email/-password to log in again")
+	}
+	sessionFile := cfg.SessionFile
+	err := skool.Login(bctx, cfg.Email, cfg.Password)
+	if errors.Is(err, skool.ErrTwoFactorRequired) {
+		if codeFn := otpSource(cfg); codeFn != nil {
+			err = submitCode(bctx, codeFn)
+			// Une session validée par code doit survivre au run, sinon le
+			// code serait redemandé à chaque fois.
+			if sessionFile == "" {
+				sessionFile = defaultSessionFile()
+			}
+		}
+	}
+	if err != nil {
+		return loginError(err)
+	}
+	cookies, err := skool.BrowserCookies(bctx)
+	if err != nil {
+		return err
+	}
+	s.cookies = cookies
+	if sessionFile != "" {
+		if err := os.MkdirAll(filepath.Dir(sessionFile), 0o700); err != nil {
+			return err
+		}
+		if err := skool.SaveCookies(sessionFile, cookies); err != nil {
+			return fmt.Errorf("cannot save -session-file: %w", err)
+		}
+		fmt.Fprintf(console, "🔑 Session saved to %s\n", sessionFile)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Code à usage unique => stdin (-otp) ou commande (-otp-command)
+// -----------------------------------------------------------------------------
+
+// otpSource retourne la source du code de vérification, nil si aucune n'est
+// configurée.
+func otpSource(cfg Config) skool.CodeFunc {
+	switch {
+	case cfg.OTPCommand != "":
+		return func(ctx context.Context) (string, error) {
+			out, err := exec.CommandContext(ctx, "sh", "-c", cfg.OTPCommand).Output()
+			if err != nil {
+				return "", fmt.Errorf("-otp-command: %w", err)
+			}
+			return strings.TrimSpace(string(out)), nil
+		}
+	case cfg.OTP:
+		return func(ctx context.Context) (string, error) {
+			fmt.Fprint(console, "🔐 Enter the verification code sent by Skool: ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return "", err
+			}
+			return strings.TrimSpace(line), nil
+		}
+	}
+	return nil
+}
+
+// submitCode demande le code à codeFn et le soumet.
+func submitCode(bctx context.Context, codeFn skool.CodeFunc) error {
+	code, err := codeFn(bctx)
+	if err != nil {
+		return err
+	}
+	return skool.SubmitCode(bctx, code)
+}
+
+// writeCookiesFile écrit les cookies de la session dans un cookies.txt
+// temporaire pour yt-dlp. Il est supprimé à la fermeture de la session.
+func (s *session) writeCookiesFile() (string, error) {
+	if len(s.cookies) == 0 {
+		return "", nil
+	}
+	f, err := os.CreateTemp("", "skool-cookies-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	s.cleanup = append(s.cleanup, func() { os.Remove(f.Name()) })
+	if err := skool.WriteNetscapeCookies(f, s.cookies); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// loginError ajoute à err une piste de résolution selon sa cause.
+func loginError(err error) error {
+	switch {
+	case errors.Is(err, skool.ErrBadCredentials):
+		return fmt.Errorf("%w (check -email/-password)", err)
+	case errors.Is(err, skool.ErrCaptchaRequired):
+		return fmt.Errorf("%w (rerun with -headless=false to solve it, or use -cookies)", err)
+	case errors.Is(err, skool.ErrTwoFactorRequired):
+		return fmt.Errorf("%w (rerun with -otp or -otp-command, or use -cookies)", err)
+	}
+	return err
+}
+
+// defaultSessionFile est l'emplacement de la session sauvée après un login par
+// code quand -session-file n'est pas donné ; il est relu automatiquement.
+func defaultSessionFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "skool-video-dl", "session.json")
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
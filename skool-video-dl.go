@@ -2,33 +2,28 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"html"
+	"io"
 	"io/fs"
 	"log"
-	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
-	"time"
 
-	"github.com/chromedp/chromedp"
+	"skool-video-dl/export"
+	"skool-video-dl/skool"
 )
 
 // -----------------------------------------------------------------------------
 // Constantes + Types
 // -----------------------------------------------------------------------------
 const (
-	browserTimeout   = 1800 * time.Second
-	defaultWaitTime  = 5
+	defaultWaitTime  = 30
 	defaultOutputDir = "downloads"
 	defaultHeadless  = true
-
-	skoolLoginURL = "https://www.skool.com/login"
+	defaultFetcher   = "chrome"
 )
 
 type Config struct {
@@ -39,953 +34,323 @@ type Config struct {
 	Wait      int
 	Headless  bool
 	Debug     bool
-}
-
-type Course struct {
-	Title string
-	URL   string
-}
-type ModuleInfo struct {
-	ID    string
-	Title string
-	URL   string
-}
-type CourseData struct {
-	Title   string
-	URL     string
-	Modules []ModuleData
-}
-type ModuleData struct {
-	Title       string
-	URL         string
-	Description string
-	Videos      []VideoRecord
-}
-type VideoRecord struct {
-	URL      string
-	Filename string
-}
-
-type Mark struct {
-	Type  string                 `json:"type"`
-	Attrs map[string]interface{} `json:"attrs,omitempty"`
-}
-type TiptapNode struct {
-	Type    string                 `json:"type"`
-	Text    string                 `json:"text,omitempty"`
-	Marks   []Mark                 `json:"marks,omitempty"`
-	Attrs   map[string]interface{} `json:"attrs,omitempty"`
-	Content []TiptapNode           `json:"content,omitempty"`
-}
+	Fetcher   string
+	AuthToken string
+	// CookiesFile est un export de cookies (Netscape ou JSON) à réutiliser.
+	CookiesFile string
+	// SessionFile persiste les cookies capturés après un login réussi.
+	SessionFile string
+	// OTP demande sur stdin le code de vérification si Skool l'exige.
+	OTP bool
+	// OTPCommand est une commande shell qui affiche ce code.
+	OTPCommand string
+	// DryRun liste ce qui serait exporté sans rien télécharger ni écrire.
+	DryRun bool
+	// PlanJSON est le fichier où écrire ce plan en JSON ("-" : stdout).
+	PlanJSON string
+	// Concurrency est le nombre de pages (onglets Chrome) lues en parallèle.
+	Concurrency int
+	// DownloadConcurrency est le nombre de yt-dlp lancés en parallèle.
+	DownloadConcurrency int
+	// Include, Exclude et Modules sélectionnent les cours / modules traités
+	// (voir skool.Filter) ; Filter est compilé à partir d'eux.
+	Include stringList
+	Exclude stringList
+	Modules string
+	Filter  *skool.Filter
+	// Format liste les formats générés ("html,md,epub") ; Formats en
+	// est la version lue.
+	Format  string
+	Formats []string
+	// Templates est lu depuis TemplateDir (-template-dir), gabarits par
+	// défaut si vide.
+	TemplateDir string
+	Templates   *export.Templates
+	// Downloader choisit les backends de téléchargement par hôte
+	// ("vimeo.com=ytdlp,*=native+ytdlp") ; Downloaders en est la version lue.
+	Downloader  string
+	Downloaders []export.DownloadRoute
+	// DownloadCmd est la commande du backend "cmd" ({url}, {output}, {referer}...).
+	DownloadCmd string
+}
+
+// console reçoit la progression et les logs : os.Stdout, ou os.Stderr quand
+// stdout est réservé au plan JSON (-dry-run -plan-json -).
+var console io.Writer = os.Stdout
+
+// stringList est un flag répétable : -include a -include b.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
 
 // -----------------------------------------------------------------------------
 // MAIN
 // -----------------------------------------------------------------------------
 func main() {
 	cfg := parseFlags()
+	if cfg.DryRun && cfg.PlanJSON == "-" {
+		// stdout ne reçoit que le JSON du plan.
+		console = os.Stderr
+	}
 	initLogging(cfg.Debug)
 	//	printBanner()
-	must(os.MkdirAll(cfg.OutputDir, fs.ModePerm))
-	ctx, cancel := setupBrowser(cfg.Headless)
-	defer cancel()
-
-	if err := loginWithCreds(ctx, cfg.Email, cfg.Password); err != nil {
-		log.Fatalf("❌ login failed: %v", err)
+	if !cfg.DryRun {
+		must(os.MkdirAll(cfg.OutputDir, fs.ModePerm))
 	}
+	ctx := context.Background()
 
-	courses, err := scrapeCourses(ctx, cfg)
+	sess, err := openSession(cfg)
 	if err != nil {
-		log.Fatalf("❌ cannot list courses: %v", err)
+		log.Fatalf("❌ login failed: %v", err)
 	}
-	fmt.Printf("🗂️  Found %d course(s)\n", len(courses))
+	defer sess.close()
 
-	var allCourses []CourseData
-	for i, c := range courses {
-		fmt.Printf("\n[%d/%d] ➜ %s\n", i+1, len(courses), c.Title)
-		courseDir := filepath.Join(cfg.OutputDir, c.Title)
-		must(os.MkdirAll(courseDir, fs.ModePerm))
+	client := skool.NewClient(sess.fetcher)
 
-		mods, err := scrapeModulesForCourse(ctx, c.URL, cfg)
-		if err != nil {
-			fmt.Printf("  ⚠️  cannot list modules: %v\n", err)
-			continue
+	courses, err := client.Courses(ctx, cfg.SkoolURL)
+	if errors.Is(err, skool.ErrNotLoggedIn) && sess.canRelogin(cfg) {
+		log.Printf("⚠️  saved session rejected (%v), logging in again\n", err)
+		if err := sess.relogin(cfg); err != nil {
+			log.Fatalf("❌ login failed: %v", err)
 		}
-		fmt.Printf("  📚 Found %d module(s)\n", len(mods))
-
-		var moduleDatas []ModuleData
-		for j, m := range mods {
-			fmt.Printf("  [%d/%d] ➜ %s\n", j+1, len(mods), m.Title)
-			modData, err := handleModule(ctx, m, courseDir, cfg)
-			if err != nil {
-				fmt.Printf("    ⚠️  %v\n", err)
-			}
-			moduleDatas = append(moduleDatas, modData)
-		}
-
-		allCourses = append(allCourses, CourseData{
-			Title:   c.Title,
-			URL:     c.URL,
-			Modules: moduleDatas,
-		})
+		client = skool.NewClient(sess.fetcher)
+		courses, err = client.Courses(ctx, cfg.SkoolURL)
 	}
-
-	fmt.Println("\n✅ All done!")
-	buildHTMLIndex(allCourses, cfg.OutputDir)
-	fmt.Printf("📁 Created %s/index.html\n", cfg.OutputDir)
-}
-
-// -----------------------------------------------------------------------------
-// parseFlags + logging + banner
-// -----------------------------------------------------------------------------
-func parseFlags() Config {
-	var c Config
-	flag.StringVar(&c.SkoolURL, "url", "", "Skool classroom URL (required)")
-	flag.StringVar(&c.Email, "email", "", "Email for Skool login")
-	flag.StringVar(&c.Password, "password", "", "Password for Skool login")
-	flag.StringVar(&c.OutputDir, "output", defaultOutputDir, "Download directory")
-	flag.IntVar(&c.Wait, "wait", defaultWaitTime, "Wait time (seconds) after nav")
-	flag.BoolVar(&c.Headless, "headless", defaultHeadless, "Run Chrome headless")
-	flag.BoolVar(&c.Debug, "debug", false, "Show debug logs")
-	flag.Parse()
-
-	if c.SkoolURL == "" {
-		log.Fatal("missing -url")
-	}
-	if c.Email == "" || c.Password == "" {
-		log.Fatal("missing -email/-password")
-	}
-	return c
-}
-func initLogging(debug bool) {
-	if debug {
-		log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	} else {
-		log.SetFlags(0)
-		log.SetOutput(os.Stdout)
+	if errors.Is(err, skool.ErrNotLoggedIn) {
+		log.Fatalf("❌ login failed: %v", err)
 	}
-}
-func printBanner() {
-	fmt.Println(`
-████████╗ ██████╗  ██████╗ ██╗     
-╚══██╔══╝██╔═══██╗██╔═══██╗██║     
-   ██║   ██║   ██║██║   ██║██║     
-   ██║   ██║   ██║██║   ██║██║     
-   ██║   ╚██████╔╝╚██████╔╝███████╗
-   ╚═╝    ╚═════╝  ╚═════╝ ╚══════╝
-  ███████╗███╗   ██╗██╗██╗   ██╗██╗     
-  ██╔════╝████╗  ██║██║██║   ██║██║     
-  █████╗  ██╔██╗ ██║██║██║   ██║██║     
-  ██╔══╝  ██║╚██╗██║██║╚██╗ ██╔╝██║     
-  ███████╗██║ ╚████║██║ ╚████╔╝ ███████╗
-  ╚══════╝╚═╝  ╚═══╝╚═╝  ╚═══╝  ╚══════╝
-              TOOL BY SNIV3L
-`)
-}
-func must(err error) {
 	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-// -----------------------------------------------------------------------------
-// Setup + login
-// -----------------------------------------------------------------------------
-func setupBrowser(headless bool) (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", headless),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.UserAgent("Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 "+
-			"(KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36"),
-	)
-	allocCtx, _ := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, cancelAlloc := chromedp.NewContext(allocCtx)
-	ctx, cancelTimeout := context.WithTimeout(ctx, browserTimeout)
-
-	return ctx, func() {
-		cancelTimeout()
-		cancelAlloc()
-	}
-}
-
-func loginWithCreds(ctx context.Context, email, pass string) error {
-	return chromedp.Run(ctx,
-		chromedp.Navigate(skoolLoginURL),
-		chromedp.WaitVisible(`input[type="email"]`),
-		chromedp.SendKeys(`input[type="email"]`, email),
-		chromedp.SendKeys(`input[type="password"]`, pass),
-		chromedp.Click(`button[type="submit"]`),
-		chromedp.Sleep(4*time.Second),
-	)
-}
-
-// -----------------------------------------------------------------------------
-// scrapeCourses => lit __NEXT_DATA__ => pageProps.allCourses
-// -----------------------------------------------------------------------------
-func scrapeCourses(ctx context.Context, cfg Config) ([]Course, error) {
-	if err := chromedp.Run(ctx,
-		chromedp.Navigate(cfg.SkoolURL),
-		chromedp.Sleep(time.Duration(cfg.Wait)*time.Second),
-	); err != nil {
-		return nil, err
-	}
-	var raw string
-	if err := chromedp.Run(ctx,
-		chromedp.WaitReady(`#__NEXT_DATA__`),
-		chromedp.EvaluateAsDevTools(
-			`document.getElementById("__NEXT_DATA__").textContent`, &raw,
-		),
-	); err != nil {
-		return nil, err
-	}
-
-	// Try to parse as a page listing all courses
-	var multi struct {
-		Props struct {
-			PageProps struct {
-				AllCourses []struct {
-					Name     string `json:"name"`
-					Metadata struct {
-						Title string `json:"title"`
-					} `json:"metadata"`
-				} `json:"allCourses"`
-			} `json:"pageProps"`
-		} `json:"props"`
-	}
-	if e := json.Unmarshal([]byte(raw), &multi); e != nil {
-		return nil, e
-	}
-
-	// If the page contains "allCourses" we return them
-	if len(multi.Props.PageProps.AllCourses) > 0 {
-		var out []Course
-		for _, c := range multi.Props.PageProps.AllCourses {
-			title := clean(c.Metadata.Title)
-			url := strings.TrimRight(cfg.SkoolURL, "/") + "/" + c.Name
-			out = append(out, Course{Title: title, URL: url})
-		}
-		return out, nil
-	}
-
-	// Otherwise we treat the provided URL as a single course
-	var single struct {
-		Props struct {
-			PageProps struct {
-				Course struct {
-					Metadata struct {
-						Title string `json:"title"`
-					} `json:"metadata"`
-				} `json:"course"`
-			} `json:"pageProps"`
-		} `json:"props"`
-	}
-	if e := json.Unmarshal([]byte(raw), &single); e != nil {
-		return nil, e
-	}
-
-	title := clean(single.Props.PageProps.Course.Metadata.Title)
-	if title == "" {
-		title = "Course"
+		log.Fatalf("❌ cannot list courses: %v", err)
 	}
-	return []Course{{Title: title, URL: cfg.SkoolURL}}, nil
-}
-
-// -----------------------------------------------------------------------------
-// scrapeModulesForCourse => children => ID + title
-// -----------------------------------------------------------------------------
-func scrapeModulesForCourse(ctx context.Context, courseURL string, cfg Config) ([]ModuleInfo, error) {
-	if err := chromedp.Run(ctx,
-		chromedp.Navigate(courseURL),
-		chromedp.Sleep(time.Duration(cfg.Wait)*time.Second),
-	); err != nil {
-		return nil, err
-	}
-	var raw string
-	if err := chromedp.Run(ctx,
-		chromedp.WaitReady(`#__NEXT_DATA__`),
-		chromedp.EvaluateAsDevTools(`document.getElementById("__NEXT_DATA__").textContent`, &raw),
-	); err != nil {
-		return nil, err
-	}
-	var data struct {
-		Props struct {
-			PageProps struct {
-				Course struct {
-					Children []struct {
-						Course struct {
-							Id       string `json:"id"`
-							Metadata struct {
-								Title string `json:"title"`
-							} `json:"metadata"`
-						} `json:"course"`
-					} `json:"children"`
-				} `json:"course"`
-			} `json:"pageProps"`
-		} `json:"props"`
-	}
-	if e := json.Unmarshal([]byte(raw), &data); e != nil {
-		return nil, e
-	}
-	var ms []ModuleInfo
-	for _, c := range data.Props.PageProps.Course.Children {
-		id := c.Course.Id
-		t := c.Course.Metadata.Title
-		if t == "" {
-			t = "Untitled"
-		}
-		u := courseURL + "?md=" + id
-		ms = append(ms, ModuleInfo{ID: id, Title: clean(t), URL: u})
+	fmt.Fprintf(console, "🗂️  Found %d course(s)\n", len(courses))
+	for _, p := range cfg.Filter.ResolveCourses(courses) {
+		log.Printf("ℹ️  -include %q matches no course, selecting modules with it\n", p)
+	}
+
+	exp := export.New(client, cfg.OutputDir)
+	exp.Out = console
+	exp.Filter = cfg.Filter
+	exp.Formats = cfg.Formats
+	exp.Templates = cfg.Templates
+	exp.Debug = cfg.Debug
+	exp.Concurrency = cfg.Concurrency
+	exp.DownloadConcurrency = cfg.DownloadConcurrency
+	exp.Downloaders = cfg.Downloaders
+	exp.DownloadCmd = cfg.DownloadCmd
+	if err := exp.LoadManifest(); err != nil {
+		log.Printf("⚠️  ignoring previous %s: %v\n", export.ManifestName, err)
+	}
+	exp.HTTPClient = skool.NewHTTPClient(skool.SkoolCookies(sess.cookies))
+	if cookiesFile, err := sess.writeCookiesFile(); err != nil {
+		log.Printf("⚠️  cannot write cookies for yt-dlp: %v\n", err)
+	} else {
+		exp.CookiesFile = cookiesFile
 	}
-	return ms, nil
-}
 
-// -----------------------------------------------------------------------------
-// handleModule => parse Tiptap => bullet => build HTML
-// -----------------------------------------------------------------------------
-func handleModule(ctx context.Context, m ModuleInfo, courseDir string, cfg Config) (ModuleData, error) {
-	modDir := filepath.Join(courseDir, m.Title)
-	modFile := filepath.Join(modDir, "module.html")
-
-	// Skip module if HTML already exists and is non-empty
-	if fileExistsAndNonZero(modFile) {
-		fmt.Println("    already downloaded, skipping")
-		return ModuleData{Title: m.Title, URL: m.URL}, nil
+	selected := selectCourses(courses, cfg.Filter)
+	if cfg.DryRun {
+		runPlan(ctx, exp, selected, cfg)
+		return
 	}
 
-	must(os.MkdirAll(modDir, fs.ModePerm))
-
-	if err := chromedp.Run(ctx,
-		chromedp.Navigate(m.URL),
-		chromedp.Sleep(time.Duration(cfg.Wait)*time.Second),
-	); err != nil {
-		return ModuleData{}, err
-	}
-	var raw string
-	if err := chromedp.Run(ctx,
-		chromedp.WaitReady(`#__NEXT_DATA__`),
-		chromedp.EvaluateAsDevTools(`document.getElementById("__NEXT_DATA__").textContent`, &raw),
-	); err != nil {
-		log.Printf("Cannot read __NEXT_DATA__ for module %s: %v\n", m.Title, err)
+	var allCourses []export.CourseData
+	if len(selected) < len(courses) {
+		fmt.Printf("🔎 %d course(s) selected\n", len(selected))
 	}
-
-	var data struct {
-		Props struct {
-			PageProps struct {
-				Course struct {
-					Children []map[string]interface{} `json:"children"`
-				} `json:"course"`
-			} `json:"pageProps"`
-		} `json:"props"`
-	}
-	_ = json.Unmarshal([]byte(raw), &data)
-
-	var desc string
-	var videoLinks []string
-	var allLinks []string
-
-	for _, ch := range data.Props.PageProps.Course.Children {
-		course, _ := ch["course"].(map[string]interface{})
-		if course == nil {
+	n := 0
+	for _, c := range courses {
+		if !cfg.Filter.KeepCourse(c) {
+			// Non sélectionné : l'index garde l'export précédent, s'il existe.
+			if cd, ok := exp.PreviousCourse(c.URL); ok {
+				allCourses = append(allCourses, cd)
+			}
 			continue
 		}
-		id, _ := course["id"].(string)
-		if id != m.ID {
+		n++
+		fmt.Printf("\n[%d/%d] ➜ %s\n", n, len(selected), c.Title)
+		cd, err := exp.ExportCourse(ctx, c)
+		if err != nil {
+			fmt.Printf("  ⚠️  %v\n", err)
 			continue
 		}
-		// Description (desc)
-		metadata, _ := course["metadata"].(map[string]interface{})
-		if metadata != nil {
-			if d, ok := metadata["desc"].(string); ok {
-				desc = d
-			}
-		}
-		// Recherche récursive de tous les videoLink dans la structure complète du module
-		fmt.Printf("    course data: %v\n", course)
-		videoLinks = extractAllVideoLinksFromAny(course)
-		fmt.Printf("    extracted videoLinks: %v\n", videoLinks)
-		break
-	}
-
-	// Ajoute aussi les liens Loom/Vimeo dans le contenu (comme avant)
-	links := extractLoomVimeoLinks(desc)
-	fmt.Printf("    videoLinks: %v\n", videoLinks)
-	for _, l := range videoLinks {
-		fmt.Printf("    processing video link: %s\n", l)
-		// Check if this is a Vimeo link
-		if strings.Contains(strings.ToLower(l), "vimeo.com") {
-			// For Vimeo URLs with /video/share?h=hash pattern, preserve the original URL
-			if strings.Contains(l, "vimeo.com/video/share?h=") {
-				fmt.Printf("    preserving original Vimeo URL: %s\n", l)
-				allLinks = append(allLinks, l)
-			} else {
-				converted := rewriteVimeoToPlayer(l)
-				fmt.Printf("    converted Vimeo URL: %s -> %s\n", l, converted)
-				allLinks = append(allLinks, converted)
-			}
-		} else {
-			// For non-Vimeo links (YouTube, Loom, etc.), add them as-is
-			fmt.Printf("    adding non-Vimeo URL as-is: %s\n", l)
-			allLinks = append(allLinks, l)
-		}
+		allCourses = append(allCourses, cd)
 	}
-	allLinks = append(allLinks, links...)
-	allLinks = uniqueStrings(allLinks)
 
-	descBullet := forceConvertTiptapBullet(desc)
-	if strings.Contains(descBullet, "[{") || strings.Contains(descBullet, "\"type\":") {
-		descBullet = "<p>" + html.EscapeString(strings.TrimSpace(desc)) + "</p>"
+	fmt.Println("\n✅ All done!")
+	printSyncReport(exp.Report())
+	if err := exp.WriteManifest(cfg.SkoolURL, allCourses); err != nil {
+		log.Printf("Cannot write %s: %v\n", export.ManifestName, err)
+	} else {
+		fmt.Printf("🧾 Wrote %s/%s\n", cfg.OutputDir, export.ManifestName)
 	}
-
-	var recs []VideoRecord
-	for i, link := range allLinks {
-		tried := false
-		// Check if this is a Vimeo URL
-		isVimeo := strings.Contains(strings.ToLower(link), "vimeo.com")
-
-		if isVimeo {
-			// For Vimeo URLs, try all variants
-			fmt.Printf("    processing Vimeo URL: %s\n", link)
-			vimeoURLs := allVimeoUrls(link)
-			for _, testURL := range vimeoURLs {
-				fmt.Printf("    downloading => %s\n", testURL)
-				fn, err := downloadVideo(testURL, modDir, i+1)
-				if err == nil {
-					recs = append(recs, VideoRecord{URL: testURL, Filename: fn})
-					tried = true
-					break
-				} else {
-					fmt.Printf("      ⚠️  fail dl: %v\n", err)
-				}
-			}
+	if exp.HasFormat(export.FormatHTML) {
+		if err := exp.Templates.BuildHTMLIndex(allCourses, cfg.OutputDir); err != nil {
+			log.Printf("Cannot create index.html: %v\n", err)
 		} else {
-			// For non-Vimeo URLs (Loom, YouTube, etc.), try the original URL directly
-			fmt.Printf("    downloading => %s\n", link)
-			fn, err := downloadVideo(link, modDir, i+1)
-			if err == nil {
-				recs = append(recs, VideoRecord{URL: link, Filename: fn})
-				tried = true
-			} else {
-				fmt.Printf("      ⚠️  fail dl: %v\n", err)
-			}
-		}
-
-		if !tried {
-			fmt.Printf("      ⚠️  all download attempts failed for: %s\n", link)
-			// Continue processing other videos even if this one fails
-			continue
+			fmt.Printf("📁 Created %s/index.html\n", cfg.OutputDir)
 		}
 	}
-
-	if err := buildModuleHTML(modFile, m.Title, descBullet, recs); err != nil {
-		log.Printf("Cannot write module.html for %s: %v\n", m.Title, err)
-	}
-	return ModuleData{
-		Title:       m.Title,
-		URL:         m.URL,
-		Description: descBullet,
-		Videos:      recs,
-	}, nil
-}
-
-// -----------------------------------------------------------------------------
-// Recherche récursive de tous les videoLink dans la structure
-// -----------------------------------------------------------------------------
-func extractAllVideoLinksFromAny(val interface{}) []string {
-	var links []string
-	switch v := val.(type) {
-	case map[string]interface{}:
-		for k, child := range v {
-			if k == "videoLink" {
-				if link, ok := child.(string); ok && link != "" {
-					links = append(links, link)
-				}
-			}
-			links = append(links, extractAllVideoLinksFromAny(child)...)
-		}
-	case []interface{}:
-		for _, item := range v {
-			links = append(links, extractAllVideoLinksFromAny(item)...)
+	if exp.HasFormat(export.FormatMarkdown) {
+		if err := exp.Templates.BuildMarkdownIndex(allCourses, cfg.OutputDir); err != nil {
+			log.Printf("Cannot create README.md: %v\n", err)
+		} else {
+			fmt.Printf("📁 Created %s/README.md\n", cfg.OutputDir)
 		}
 	}
-	return links
-}
-
-// -----------------------------------------------------------------------------
-// Essayer toutes les variantes d'URL Vimeo
-// -----------------------------------------------------------------------------
-func allVimeoUrls(link string) []string {
-	id := ""
-	hash := ""
-
-	if u, err := url.Parse(link); err == nil {
-		segs := strings.Split(strings.Trim(u.Path, "/"), "/")
-		if len(segs) > 0 {
-			if segs[0] == "video" {
-				if len(segs) > 1 {
-					// Special case: if path is /video/share, use hash as ID
-					if segs[1] == "share" {
-						hash = u.Query().Get("h")
-						id = hash // Use hash as the actual video ID
-					} else {
-						id = segs[1]
-					}
-				}
-				if len(segs) > 2 && segs[1] != "share" {
-					hash = segs[2]
-				}
+	if exp.HasFormat(export.FormatEPUB) {
+		for _, cd := range allCourses {
+			path := filepath.Join(cfg.OutputDir, filepath.FromSlash(cd.Dir)+".epub")
+			if err := exp.Templates.BuildEPUB(path, cd); err != nil {
+				log.Printf("Cannot create %s: %v\n", path, err)
 			} else {
-				id = segs[0]
-				if len(segs) > 1 {
-					hash = segs[1]
-				}
+				fmt.Printf("📚 Created %s\n", path)
 			}
 		}
-		// Override hash from query parameter if present
-		if h := u.Query().Get("h"); h != "" {
-			hash = h
-		}
-	} else {
-		re := regexp.MustCompile(`vimeo\.com/(?:video/)?(\d+)(?:/([a-zA-Z0-9]+))?`)
-		m := re.FindStringSubmatch(link)
-		if len(m) > 1 {
-			id = m[1]
-		}
-		if len(m) > 2 {
-			hash = m[2]
-		}
-	}
-
-	// Fallback: if we still don't have a valid ID, try to extract from hash if it looks like an ID
-	if id == "" || id == "share" {
-		// Check if hash looks like a valid ID (alphanumeric)
-		if hash != "" && regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString(hash) {
-			id = hash
-		}
-	}
-
-	var urls []string
-	if id != "" && id != "share" {
-		if hash != "" && hash != id {
-			urls = append(urls, fmt.Sprintf("https://player.vimeo.com/video/%s?h=%s", id, hash))
-		}
-		urls = append(urls, fmt.Sprintf("https://player.vimeo.com/video/%s", id))
-		if hash != "" && hash != id {
-			urls = append(urls, fmt.Sprintf("https://vimeo.com/%s/%s", id, hash))
-		}
-		urls = append(urls, fmt.Sprintf("https://vimeo.com/%s", id))
-	}
-	if !contains(urls, link) {
-		urls = append(urls, link)
 	}
-	return urls
-}
-func contains(list []string, v string) bool {
-	for _, s := range list {
-		if s == v {
-			return true
-		}
-	}
-	return false
 }
 
-// -----------------------------------------------------------------------------
-// Tiptap -> HTML natif lisible (p, h1, ul, li, a, strong, etc.)
-// -----------------------------------------------------------------------------
-func forceConvertTiptapBullet(desc string) string {
-	if desc == "" {
-		return ""
-	}
-	desc = strings.ReplaceAll(desc, "[v2]", "")
-	idx := strings.Index(desc, "[{")
-	if idx < 0 {
-		return "<p>" + html.EscapeString(strings.TrimSpace(desc)) + "</p>"
-	}
-	j := desc[idx:]
-	u := html.UnescapeString(j)
-
-	var nodes []TiptapNode
-	if err := json.Unmarshal([]byte(u), &nodes); err == nil {
-		return strings.TrimSpace(renderTiptapNodesHTML(nodes))
-	}
-	var root struct {
-		Content []TiptapNode `json:"content"`
-	}
-	if err := json.Unmarshal([]byte(u), &root); err == nil && len(root.Content) > 0 {
-		return strings.TrimSpace(renderTiptapNodesHTML(root.Content))
-	}
-	return "<p>" + html.EscapeString(strings.TrimSpace(desc)) + "</p>"
-}
-func renderTiptapNodesHTML(nodes []TiptapNode) string {
-	var sb strings.Builder
-	for _, node := range nodes {
-		sb.WriteString(renderTiptapNodeHTML(node))
-	}
-	return sb.String()
-}
-func renderTiptapNodeHTML(node TiptapNode) string {
-	switch node.Type {
-	case "heading":
-		level := 1
-		if l, ok := node.Attrs["level"].(float64); ok {
-			level = int(l)
-		}
-		headingTag := fmt.Sprintf("h%d", level)
-		text := strings.TrimSpace(renderTiptapNodesHTML(node.Content))
-		return fmt.Sprintf("<%s>%s</%s>\n", headingTag, text, headingTag)
-	case "paragraph":
-		txt := strings.TrimSpace(renderTiptapNodesHTML(node.Content))
-		if txt == "" {
-			return ""
-		}
-		return "<p>" + txt + "</p>\n"
-	case "bulletList":
-		return "<ul>\n" + renderListItems(node.Content) + "</ul>\n"
-	case "orderedList":
-		return "<ol>\n" + renderListItems(node.Content) + "</ol>\n"
-	case "listItem":
-		return "<li>" + strings.TrimSpace(renderTiptapNodesHTML(node.Content)) + "</li>\n"
-	case "text":
-		txt := html.EscapeString(node.Text)
-		for _, mark := range node.Marks {
-			switch mark.Type {
-			case "bold":
-				txt = "<strong>" + txt + "</strong>"
-			case "italic":
-				txt = "<em>" + txt + "</em>"
-			case "link":
-				href, _ := mark.Attrs["href"].(string)
-				txt = "<a href=\"" + html.EscapeString(href) + "\" target=\"_blank\">" + txt + "</a>"
-			}
-		}
-		return txt
-	case "hardBreak":
-		return "<br>\n"
-	case "blockquote":
-		content := strings.TrimSpace(renderTiptapNodesHTML(node.Content))
-		return "<blockquote>" + content + "</blockquote>\n"
-	default:
-		if len(node.Content) > 0 {
-			return renderTiptapNodesHTML(node.Content)
+// selectCourses retourne les cours retenus par f.
+func selectCourses(courses []skool.Course, f *skool.Filter) []skool.Course {
+	var out []skool.Course
+	for _, c := range courses {
+		if f.KeepCourse(c) {
+			out = append(out, c)
 		}
 	}
-	return ""
-}
-func renderListItems(nodes []TiptapNode) string {
-	var sb strings.Builder
-	for _, n := range nodes {
-		sb.WriteString(renderTiptapNodeHTML(n))
-	}
-	return sb.String()
+	return out
 }
 
-// -----------------------------------------------------------------------------
-// extractLoomVimeoLinks => parse la version Node (HTML) pour .Marks => link
-// -----------------------------------------------------------------------------
-func extractLoomVimeoLinks(desc string) []string {
-	if !strings.Contains(desc, "[{") {
-		return nil
-	}
-	idx := strings.Index(desc, "[{")
-	if idx < 0 {
-		return nil
-	}
-	j := desc[idx:]
-	u := html.UnescapeString(j)
-
-	var nds []TiptapNode
-	if err := json.Unmarshal([]byte(u), &nds); err == nil {
-		return filterLinksFromTiptap(nds)
-	}
-	var root struct {
-		Content []TiptapNode `json:"content"`
-	}
-	if err := json.Unmarshal([]byte(u), &root); err == nil && len(root.Content) > 0 {
-		return filterLinksFromTiptap(root.Content)
-	}
-	return nil
-}
-func filterLinksFromTiptap(nodes []TiptapNode) []string {
-	var out []string
-	traverseNodesForLinks(nodes, &out)
-	return filterLoomVimeo(uniqueStrings(out))
-}
-func traverseNodesForLinks(nodes []TiptapNode, out *[]string) {
-	for _, n := range nodes {
-		for _, mk := range n.Marks {
-			if mk.Type == "link" {
-				if href, ok := mk.Attrs["href"].(string); ok {
-					*out = append(*out, href)
-				}
-			}
+// runPlan affiche (et écrit en JSON avec -plan-json) ce que l'export
+// produirait, sans rien télécharger ni écrire dans -output.
+func runPlan(ctx context.Context, exp *export.Exporter, courses []skool.Course, cfg Config) {
+	// Avec -plan-json -, console est stderr : stdout ne reçoit que le JSON.
+	out := console
+	plan := export.Plan{SourceURL: cfg.SkoolURL, OutputDir: cfg.OutputDir}
+	for i, c := range courses {
+		fmt.Fprintf(out, "[%d/%d] ➜ %s\n", i+1, len(courses), c.Title)
+		pc, err := exp.PlanCourse(ctx, c)
+		if err != nil {
+			pc.Error = err.Error()
 		}
-		if len(n.Content) > 0 {
-			traverseNodesForLinks(n.Content, out)
+		plan.Courses = append(plan.Courses, pc)
+	}
+	plan.WriteText(out)
+	if cfg.PlanJSON != "" {
+		if err := plan.WriteJSON(cfg.PlanJSON); err != nil {
+			log.Fatalf("❌ cannot write plan: %v", err)
 		}
 	}
 }
-func filterLoomVimeo(links []string) []string {
-	var out []string
-	for _, s := range links {
-		ll := strings.ToLower(s)
-		if strings.Contains(ll, "loom.com") || strings.Contains(ll, "vimeo.com") {
-			out = append(out, s)
+
+// printSyncReport résume ce qui a changé depuis le run précédent.
+func printSyncReport(r export.SyncReport) {
+	fmt.Printf("🔄 %d added, %d changed, %d unchanged, %d removed\n",
+		len(r.Added), len(r.Changed), len(r.Unchanged), len(r.Removed))
+	for _, l := range []struct {
+		label string
+		names []string
+	}{{"+", r.Added}, {"~", r.Changed}, {"-", r.Removed}} {
+		for _, n := range l.names {
+			fmt.Printf("   %s %s\n", l.label, n)
 		}
 	}
-	return out
 }
 
 // -----------------------------------------------------------------------------
-// rewriteVimeoToPlayer => vimeo.com/\d+ => player
+// parseFlags + logging + banner
 // -----------------------------------------------------------------------------
-var reVimeoNum = regexp.MustCompile(`(?i)vimeo\.com/(?:video/)?(\d+)`)
-
-func rewriteVimeoToPlayer(link string) string {
-	if strings.Contains(link, "player.vimeo.com") {
-		return link
-	}
-
-	u, err := url.Parse(link)
-	if err != nil {
-		return link
-	}
-
-	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
-	id := ""
-	hash := u.Query().Get("h")
-
-	if len(segs) > 0 {
-		if segs[0] == "video" {
-			if len(segs) > 1 {
-				// Special case: if path is /video/share, use hash as ID
-				if segs[1] == "share" {
-					id = hash // Use hash as the actual video ID
-				} else {
-					id = segs[1]
-				}
-			}
-			if len(segs) > 2 && segs[1] != "share" {
-				hash = segs[2]
-			}
-		} else {
-			id = segs[0]
-			if len(segs) > 1 {
-				hash = segs[1]
-			}
-		}
-	}
-
-	if id == "" {
-		m := reVimeoNum.FindStringSubmatch(link)
-		if len(m) > 1 {
-			id = m[1]
-		}
-	}
+func parseFlags() Config {
+	var c Config
+	flag.StringVar(&c.SkoolURL, "url", "", "Skool classroom URL (required)")
+	flag.StringVar(&c.Email, "email", "", "Email for Skool login")
+	flag.StringVar(&c.Password, "password", "", "Password for Skool login")
+	flag.StringVar(&c.OutputDir, "output", defaultOutputDir, "Download directory")
+	flag.IntVar(&c.Wait, "wait", defaultWaitTime, "Max wait (seconds) for a page to be ready")
+	flag.BoolVar(&c.Headless, "headless", defaultHeadless, "Run Chrome headless")
+	flag.BoolVar(&c.Debug, "debug", false, "Show debug logs")
+	flag.StringVar(&c.Fetcher, "fetcher", defaultFetcher, "Page fetcher: chrome or http (no browser)")
+	flag.StringVar(&c.AuthToken, "auth-token", "", "Skool auth_token cookie value (skips the login form)")
+	flag.StringVar(&c.CookiesFile, "cookies", "", "Cookies file to reuse (Netscape cookies.txt or JSON export)")
+	flag.StringVar(&c.SessionFile, "session-file", "", "File where the session cookies are saved after login and reused next runs")
+	flag.BoolVar(&c.DryRun, "dry-run", false, "List what would be exported (and what already exists) without downloading or writing anything")
+	flag.StringVar(&c.PlanJSON, "plan-json", "", "With -dry-run, also write the plan as JSON to this file (- for stdout)")
+	flag.IntVar(&c.Concurrency, "concurrency", 1, "Number of modules exported in parallel (Chrome tabs)")
+	flag.IntVar(&c.DownloadConcurrency, "download-concurrency", 1, "Number of parallel yt-dlp downloads")
+	flag.BoolVar(&c.OTP, "otp", false, "Prompt on stdin for the login verification code if Skool asks for one")
+	flag.StringVar(&c.OTPCommand, "otp-command", "", "Shell command printing the login verification code")
+	flag.Var(&c.Include, "include", "Only export matching courses/modules: glob, /regexp/, ID or slug; prefix with course: or module:, otherwise it selects the courses it matches, or the modules if it matches no course (repeatable)")
+	flag.Var(&c.Exclude, "exclude", "Skip matching courses/modules, same syntax as -include (repeatable)")
+	flag.StringVar(&c.Modules, "modules", "", "Only export modules at these positions in each course, e.g. 3-7,12")
+	flag.StringVar(&c.Format, "format", export.FormatHTML, "Comma-separated formats: html, md (Markdown with YAML front-matter), epub (one book per course)")
+	flag.StringVar(&c.TemplateDir, "template-dir", "", "Directory with HTML templates overriding the built-in ones (module.html, index.html, style.css)")
+	flag.StringVar(&c.Downloader, "downloader", export.DefaultDownloadRoutes, "Video downloaders per host, tried in order: host=backend+backend,... with backends native, ytdlp, cmd (* = other hosts)")
+	flag.StringVar(&c.DownloadCmd, "download-cmd", "", "Shell command for the cmd downloader; {url}, {output}, {dir}, {name} and {referer} are replaced")
+	flag.Parse()
 
-	// Fallback: if we still don't have a valid ID, try to extract from hash if it looks like an ID
-	if id == "" || id == "share" {
-		// Check if hash looks like a valid ID (alphanumeric)
-		if hash != "" && regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString(hash) {
-			id = hash
-		}
+	if c.SkoolURL == "" {
+		log.Fatal("missing -url")
 	}
-
-	if id == "" || id == "share" {
-		return link
+	if c.Concurrency < 1 || c.DownloadConcurrency < 1 {
+		log.Fatal("-concurrency and -download-concurrency must be >= 1")
 	}
-
-	if hash != "" && hash != id {
-		return fmt.Sprintf("https://player.vimeo.com/video/%s?h=%s", id, hash)
+	if c.Fetcher != "chrome" && c.Fetcher != "http" {
+		log.Fatalf("invalid -fetcher %q (want chrome or http)", c.Fetcher)
 	}
-	return fmt.Sprintf("https://player.vimeo.com/video/%s", id)
-}
-
-// -----------------------------------------------------------------------------
-// buildModuleHTML => desc dans <div class="content">, liens natifs HTML
-// -----------------------------------------------------------------------------
-func buildModuleHTML(path string, title, desc string, videos []VideoRecord) error {
-	f, err := os.Create(path)
+	filter, err := skool.NewFilter(c.Include, c.Exclude, c.Modules)
 	if err != nil {
-		return err
+		log.Fatalf("invalid selection: %v", err)
 	}
-	defer f.Close()
-
-	fmt.Fprintf(f, `<!DOCTYPE html>
-<html lang="en">
-<head>
-  <meta charset="utf-8">
-  <title>%s</title>
-  <style>
-    body { font-family: Arial, sans-serif; line-height: 1.5; max-width: 800px; margin: 2rem auto; padding: 1rem; background-color: #fff; color: #333; }
-    h1, h2, h3, h4, h5, h6 { margin-top: 1.2em; margin-bottom: 0.6em; color: #222; }
-    .content { font-family: inherit; margin-bottom: 2em; }
-    p { margin: 0.8em 0; }
-    ul, ol { margin: 0.6em 0 0.6em 2em; }
-    li { margin: 0.4em 0; }
-    a { color: #007bff; text-decoration: none; }
-    a:hover { text-decoration: underline; }
-    strong { font-weight: 700; }
-    em { font-style: italic; }
-    blockquote { color: #666; border-left: 4px solid #eee; margin: 0.8em 0; padding-left: 1em; font-style: italic;}
-    .video-wrapper { margin-bottom: 2em; }
-    .video-wrapper p { margin-bottom: 0.3em; }
-    br { margin-bottom: 8px; }
-  </style>
-</head>
-<body>`, htmlEscape(title))
-
-	fmt.Fprintf(f, `<h1>%s</h1>`, htmlEscape(title))
-
-	if desc != "" {
-		fmt.Fprintf(f, `<div class="content">%s</div>`, desc)
-	} else {
-		fmt.Fprintln(f, `<p><i>Aucun contenu Tiptap</i></p>`)
+	c.Filter = filter
+	if c.Formats, err = export.ParseFormats(c.Format); err != nil {
+		log.Fatalf("invalid -format: %v", err)
 	}
-
-	if len(videos) > 0 {
-		fmt.Fprintln(f, `<h2>Vidéos (offline)</h2>`)
-		for _, v := range videos {
-			base := filepath.Base(v.Filename)
-			fmt.Fprintf(f, `
-<div class="video-wrapper">
-  <p><b>%s</b> (<i>%s</i>)</p>
-  <video controls style="width:100%%; max-width:600px;">
-    <source src="%s" type="video/mp4">
-    Votre navigateur ne supporte pas la vidéo HTML5.
-  </video>
-</div>`, htmlEscape(base), htmlEscape(v.URL), htmlEscape(base))
-		}
-	} else {
-		fmt.Fprintln(f, `<p><i>Aucune vidéo dans ce module</i></p>`)
+	if c.Templates, err = export.LoadTemplates(c.TemplateDir); err != nil {
+		log.Fatalf("invalid -template-dir: %v", err)
 	}
-
-	fmt.Fprintln(f, `</body></html>`)
-	return nil
-}
-
-// -----------------------------------------------------------------------------
-// downloadVideo => yt-dlp
-// -----------------------------------------------------------------------------
-func downloadVideo(url string, outDir string, idx int) (string, error) {
-	final := filepath.Join(outDir, fmt.Sprintf("video-%02d.mp4", idx))
-	if fileExistsAndNonZero(final) {
-		fmt.Printf("      skipping existing file %s\n", filepath.Base(final))
-		return final, nil
+	if c.Downloaders, err = export.ParseDownloadRoutes(c.Downloader); err != nil {
+		log.Fatalf("invalid -downloader: %v", err)
 	}
-
-	name := fmt.Sprintf("video-%02d.%%(ext)s", idx)
-	outputTemplate := filepath.Join(outDir, name)
-
-	// Retry logic for downloading videos
-	maxRetries := 3
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			fmt.Printf("      retrying download (attempt %d/%d)\n", attempt, maxRetries)
-			// Add a small delay between retries
-			time.Sleep(time.Duration(attempt) * time.Second)
-		}
-
-		cmd := exec.Command("yt-dlp", "-o", outputTemplate, url)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			if attempt == maxRetries {
-				return "", err
-			}
-			fmt.Printf("      download failed: %v, retrying...\n", err)
-			continue
-		}
-		return final, nil
+	if export.UsesBackend(c.Downloaders, export.BackendCommand) && c.DownloadCmd == "" {
+		log.Fatal("-downloader uses cmd but -download-cmd is empty")
 	}
-	return final, nil
-}
-
-// -----------------------------------------------------------------------------
-// buildHTMLIndex => index global
-// -----------------------------------------------------------------------------
-func buildHTMLIndex(all []CourseData, outDir string) {
-	fp := filepath.Join(outDir, "index.html")
-	f, err := os.Create(fp)
-	if err != nil {
-		log.Printf("Cannot create index.html: %v\n", err)
-		return
+	if c.SessionFile == "" && fileExists(defaultSessionFile()) {
+		c.SessionFile = defaultSessionFile()
 	}
-	defer f.Close()
-
-	fmt.Fprintln(f, `<!DOCTYPE html><html lang="en"><head><meta charset="utf-8"><title>Skool Export Offline</title></head><body>`)
-	fmt.Fprintln(f, `<h1>Skool Export Offline</h1>`)
-	for _, c := range all {
-		cDir := clean(c.Title)
-		fmt.Fprintf(f, `<h2>%s</h2><ul>`, htmlEscape(c.Title))
-		for _, m := range c.Modules {
-			mDir := clean(m.Title)
-			link := filepath.Join(cDir, mDir, "module.html")
-			fmt.Fprintf(f, `<li><a href="%s">%s</a></li>`, link, htmlEscape(m.Title))
-		}
-		fmt.Fprintln(f, "</ul>")
+	if c.AuthToken == "" && c.CookiesFile == "" && !fileExists(c.SessionFile) &&
+		(c.Email == "" || c.Password == "") {
+		log.Fatal("missing -email/-password (or -auth-token, -cookies, -session-file)")
 	}
-	fmt.Fprintln(f, "</body></html>")
-}
-
-// -----------------------------------------------------------------------------
-// Helpers
-// -----------------------------------------------------------------------------
-func htmlEscape(s string) string {
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
-	return s
-}
-
-func clean(input string) string {
-	s := strings.TrimSpace(input)
-	s = removeAccents(s)
-	bad := []string{"/", "\\", ":", "?", "*", "\"", "<", ">", "|", "(", ")", "’", "'", "“", "”", "‘", "«", "»", "…", "!", "#", "&", "=", "+", "$"}
-	for _, c := range bad {
-		s = strings.ReplaceAll(s, c, "-")
-	}
-	s = strings.Join(strings.Fields(s), " ")
-	return s
+	return c
 }
 
-func removeAccents(input string) string {
-	var sb strings.Builder
-	for _, r := range input {
-		if r > 127 {
-			switch r {
-			case 'é', 'è', 'ê', 'ë':
-				r = 'e'
-			case 'à', 'â':
-				r = 'a'
-			case 'ô':
-				r = 'o'
-			case 'ù', 'û':
-				r = 'u'
-			case 'î', 'ï':
-				r = 'i'
-			default:
-				r = ' '
-			}
-		}
-		sb.WriteRune(r)
+func initLogging(debug bool) {
+	if debug {
+		log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	} else {
+		log.SetFlags(0)
+		log.SetOutput(console)
 	}
-	return sb.String()
 }
-
-func uniqueStrings(arr []string) []string {
-	seen := map[string]bool{}
-	var out []string
-	for _, s := range arr {
-		if !seen[s] {
-			seen[s] = true
-			out = append(out, s)
-		}
-	}
-	return out
+func printBanner() {
+	fmt.Print(`
+████████╗ ██████╗  ██████╗ ██╗     
+╚══██╔══╝██╔═══██╗██╔═══██╗██║     
+   ██║   ██║   ██║██║   ██║██║     
+   ██║   ██║   ██║██║   ██║██║     
+   ██║   ╚██████╔╝╚██████╔╝███████╗
+   ╚═╝    ╚═════╝  ╚═════╝ ╚══════╝
+  ███████╗███╗   ██╗██╗██╗   ██╗██╗     
+  ██╔════╝████╗  ██║██║██║   ██║██║     
+  █████╗  ██╔██╗ ██║██║██║   ██║██║     
+  ██╔══╝  ██║╚██╗██║██║╚██╗ ██╔╝██║     
+  ███████╗██║ ╚████║██║ ╚████╔╝ ███████╗
+  ╚══════╝╚═╝  ╚═══╝╚═╝  ╚═══╝  ╚══════╝
+              TOOL BY SNIV3L
+`)
 }
-
-func fileExistsAndNonZero(path string) bool {
-	info, err := os.Stat(path)
+func must(err error) {
 	if err != nil {
-		return false
+		log.Fatal(err)
 	}
-	return info.Size() > 0
 }
@@ -0,0 +1,157 @@
+package skool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// Erreurs d'authentification retournées par Login et les fetchers. Elles
+// sont enveloppées avec le détail affiché par Skool ; tester avec errors.Is.
+var (
+	ErrBadCredentials    = errors.New("bad email or password")
+	ErrCaptchaRequired   = errors.New("captcha required")
+	ErrTwoFactorRequired = errors.New("verification code required")
+	ErrInvalidCode       = errors.New("verification code rejected")
+	ErrLoginTimeout      = errors.New("login not confirmed")
+	ErrNotLoggedIn       = errors.New("not logged in (session missing or expired)")
+)
+
+// LoginTimeout borne l'attente de la confirmation du login.
+const LoginTimeout = 30 * time.Second
+
+// -----------------------------------------------------------------------------
+// Login => formulaire + vérification
+// -----------------------------------------------------------------------------
+
+// Login se connecte avec email + mot de passe via le formulaire Skool, puis
+// vérifie que la connexion a réussi : l'URL quitte /login et le cookie
+// AuthCookie est posé. ctx doit être un contexte chromedp (voir SetupBrowser).
+func Login(ctx context.Context, email, pass string) error {
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(LoginURL),
+		chromedp.WaitVisible(`input[type="email"]`),
+		chromedp.SendKeys(`input[type="email"]`, email),
+		chromedp.SendKeys(`input[type="password"]`, pass),
+		chromedp.Click(`button[type="submit"]`),
+	); err != nil {
+		return err
+	}
+	return WaitLoggedIn(ctx, LoginTimeout)
+}
+
+// CodeFunc fournit le code à usage unique (email ou application) demandé par
+// Skool après le mot de passe.
+type CodeFunc func(ctx context.Context) (string, error)
+
+// codeInputSel cible le champ de code à usage unique.
+const codeInputSel = `input[autocomplete="one-time-code"], input[name*="code" i], input[placeholder*="code" i]`
+
+// SubmitCode saisit code dans le champ affiché après un ErrTwoFactorRequired
+// et attend la confirmation du login.
+func SubmitCode(ctx context.Context, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return fmt.Errorf("%w: empty code", ErrInvalidCode)
+	}
+	if err := chromedp.Run(ctx,
+		chromedp.WaitVisible(codeInputSel, chromedp.ByQuery),
+		chromedp.Focus(codeInputSel, chromedp.ByQuery),
+		// Les champs à un chiffre par case avancent tout seuls à la frappe.
+		chromedp.KeyEvent(code),
+		chromedp.KeyEvent(kb.Enter),
+	); err != nil {
+		return err
+	}
+	return waitLoggedIn(ctx, LoginTimeout, true)
+}
+
+// loginState est l'état de la page de login lu dans le navigateur.
+type loginState struct {
+	URL     string `json:"url"`
+	Captcha bool   `json:"captcha"`
+	Code    bool   `json:"code"`
+	Error   string `json:"error"`
+}
+
+// loginStateJS lit l'état de la page : challenge captcha visible, champ de
+// code à usage unique, message d'erreur du formulaire.
+const loginStateJS = `(() => {
+  const visible = el => { if (!el) return false; const r = el.getBoundingClientRect(); return r.width > 0 && r.height > 0; };
+  const any = sel => Array.from(document.querySelectorAll(sel)).some(visible);
+  const err = Array.from(document.querySelectorAll('[role="alert"], [class*="error" i], [class*="Error"]'))
+    .filter(visible).map(el => el.innerText.trim()).find(t => t);
+  return {
+    url: location.href,
+    captcha: any('iframe[src*="recaptcha/api2/bframe"], iframe[src*="recaptcha/enterprise/bframe"], iframe[src*="hcaptcha.com"][title*="challenge" i], iframe[src*="challenges.cloudflare.com"]'),
+    code: any('` + codeInputSel + `'),
+    error: err || ""
+  };
+})()`
+
+// WaitLoggedIn attend, après soumission du formulaire, que le login soit
+// confirmé ou qu'un obstacle apparaisse (erreur, captcha, code).
+func WaitLoggedIn(ctx context.Context, timeout time.Duration) error {
+	return waitLoggedIn(ctx, timeout, false)
+}
+
+func waitLoggedIn(ctx context.Context, timeout time.Duration, codeSent bool) error {
+	deadline := time.Now().Add(timeout)
+	var last loginState
+	for {
+		if err := chromedp.Run(ctx, chromedp.Evaluate(loginStateJS, &last)); err != nil {
+			return err
+		}
+		cookies, err := BrowserCookies(ctx)
+		if err != nil {
+			return err
+		}
+		done, err := classifyLogin(last, HasAuthCookie(cookies), codeSent)
+		if done {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w after %s (still on %s)", ErrLoginTimeout, timeout, last.URL)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// classifyLogin décide si l'état st est final : succès (nil) ou erreur typée.
+// codeSent indique qu'un code à usage unique vient d'être soumis : le champ de
+// code encore visible n'est alors pas un nouvel obstacle.
+func classifyLogin(st loginState, hasAuth, codeSent bool) (done bool, err error) {
+	onLogin := true
+	if u, e := url.Parse(st.URL); e == nil {
+		onLogin = strings.HasPrefix(u.Path, "/login")
+	}
+	switch {
+	case st.Captcha:
+		return true, ErrCaptchaRequired
+	case codeSent && st.Error != "":
+		return true, fmt.Errorf("%w: %s", ErrInvalidCode, st.Error)
+	case st.Code && !codeSent:
+		return true, ErrTwoFactorRequired
+	case st.Error != "" && onLogin:
+		return true, fmt.Errorf("%w: %s", ErrBadCredentials, st.Error)
+	case hasAuth && !onLogin:
+		return true, nil
+	}
+	return false, nil
+}
+
+// isLoginURL indique si u est la page de login (redirection d'une page privée
+// sans session valide).
+func isLoginURL(u *url.URL) bool {
+	return u != nil && strings.HasPrefix(u.Path, "/login")
+}
@@ -0,0 +1,179 @@
+package skool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// -----------------------------------------------------------------------------
+// Setup
+// -----------------------------------------------------------------------------
+
+// SetupBrowser démarre Chrome et retourne un contexte chromedp prêt à l'emploi.
+func SetupBrowser(headless bool) (context.Context, context.CancelFunc) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", headless),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.UserAgent(userAgent),
+	)
+	allocCtx, cancelExec := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancelAlloc := chromedp.NewContext(allocCtx)
+	ctx, cancelTimeout := context.WithTimeout(ctx, BrowserTimeout)
+
+	return ctx, func() {
+		cancelTimeout()
+		cancelAlloc()
+		cancelExec()
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Cookies navigateur <=> net/http
+// -----------------------------------------------------------------------------
+
+// BrowserCookies retourne les cookies skool.com du navigateur.
+func BrowserCookies(ctx context.Context) ([]*http.Cookie, error) {
+	var cks []*network.Cookie
+	if err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cks, err = network.GetCookies().WithURLs([]string{BaseURL}).Do(ctx)
+		return err
+	})); err != nil {
+		return nil, err
+	}
+	out := make([]*http.Cookie, 0, len(cks))
+	for _, c := range cks {
+		hc := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+		}
+		if c.Expires > 0 {
+			hc.Expires = time.Unix(int64(c.Expires), 0)
+		}
+		out = append(out, hc)
+	}
+	return out, nil
+}
+
+// SetBrowserCookies injecte cookies dans le navigateur, pour réutiliser une
+// session existante sans passer par le formulaire de login.
+func SetBrowserCookies(ctx context.Context, cookies []*http.Cookie) error {
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, c := range cookies {
+			p := network.SetCookie(c.Name, c.Value).
+				WithURL(BaseURL).
+				WithPath(c.Path).
+				WithSecure(c.Secure).
+				WithHTTPOnly(c.HttpOnly)
+			if c.Domain != "" {
+				p = p.WithDomain(c.Domain)
+			}
+			if !c.Expires.IsZero() {
+				exp := cdp.TimeSinceEpoch(c.Expires)
+				p = p.WithExpires(&exp)
+			}
+			if err := p.Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// -----------------------------------------------------------------------------
+// ChromeFetcher => Navigate + attente de #__NEXT_DATA__ prêt
+// -----------------------------------------------------------------------------
+
+// pollInterval est l'intervalle entre deux lectures de la page en attente.
+const pollInterval = 200 * time.Millisecond
+
+// nextDataJS lit l'URL courante et le contenu de #__NEXT_DATA__ ("" si absent).
+const nextDataJS = `(() => {
+  const el = document.getElementById("__NEXT_DATA__");
+  return { url: location.href, data: el ? el.textContent : "" };
+})()`
+
+// ChromeFetcher lit #__NEXT_DATA__ en chargeant la page dans Chrome. Il
+// dispose d'un ou plusieurs onglets : autant de pages peuvent être lues en
+// parallèle.
+type ChromeFetcher struct {
+	// tabs contient les onglets libres.
+	tabs chan context.Context
+	// Timeout borne l'attente d'une page prête ; ce n'est pas un délai fixe.
+	Timeout time.Duration
+}
+
+// NewChromeFetcher retourne un fetcher qui navigue dans le navigateur
+// browser (voir SetupBrowser) et attend au plus timeout qu'une page soit
+// prête. tabs > 1 ouvre des onglets supplémentaires, fermés avec browser.
+func NewChromeFetcher(browser context.Context, timeout time.Duration, tabs int) *ChromeFetcher {
+	if tabs < 1 {
+		tabs = 1
+	}
+	f := &ChromeFetcher{tabs: make(chan context.Context, tabs), Timeout: timeout}
+	f.tabs <- browser
+	for i := 1; i < tabs; i++ {
+		tab, _ := chromedp.NewContext(browser)
+		f.tabs <- tab
+	}
+	return f
+}
+
+// NextData implémente PageFetcher. Après la navigation, #__NEXT_DATA__ est
+// relu jusqu'à ce que ready l'accepte : on rend la main dès que la page est
+// prête au lieu d'attendre un délai fixe.
+func (f *ChromeFetcher) NextData(ctx context.Context, pageURL string, ready ReadyFunc) (string, error) {
+	var tab context.Context
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case tab = <-f.tabs:
+	}
+	defer func() { f.tabs <- tab }()
+
+	tctx, cancel := context.WithTimeout(tab, f.Timeout)
+	defer cancel()
+
+	if err := chromedp.Run(tctx, chromedp.Navigate(pageURL)); err != nil {
+		return "", fmt.Errorf("open %s: %w", pageURL, err)
+	}
+	var st struct {
+		URL  string `json:"url"`
+		Data string `json:"data"`
+	}
+	for {
+		if err := chromedp.Run(tctx, chromedp.Evaluate(nextDataJS, &st)); err != nil {
+			if tctx.Err() == nil {
+				return "", err
+			}
+		}
+		if u, err := url.Parse(st.URL); err == nil && isLoginURL(u) {
+			return "", fmt.Errorf("open %s: %w", pageURL, ErrNotLoggedIn)
+		}
+		if st.Data != "" && (ready == nil || ready(st.Data)) {
+			return st.Data, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-tctx.Done():
+			if st.Data == "" {
+				return "", fmt.Errorf("open %s: %w", pageURL, ErrNoNextData)
+			}
+			return "", fmt.Errorf("open %s: %w after %s", pageURL, ErrPageNotReady, f.Timeout)
+		case <-time.After(pollInterval):
+		}
+	}
+}
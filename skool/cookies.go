@@ -0,0 +1,199 @@
+package skool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+// Fichiers de cookies : Netscape (cookies.txt) ou export JSON
+// -----------------------------------------------------------------------------
+
+// jsonCookie couvre les exports JSON courants (extensions Cookie-Editor /
+// EditThisCookie, storageState Playwright) et le format de SaveCookies.
+type jsonCookie struct {
+	Name           string  `json:"name"`
+	Value          string  `json:"value"`
+	Domain         string  `json:"domain"`
+	Path           string  `json:"path"`
+	Secure         bool    `json:"secure"`
+	HTTPOnly       bool    `json:"httpOnly"`
+	ExpirationDate float64 `json:"expirationDate,omitempty"`
+	Expires        float64 `json:"expires,omitempty"`
+}
+
+// LoadCookies lit un fichier de cookies au format Netscape (cookies.txt) ou
+// JSON (tableau de cookies ou objet {"cookies": [...]}).
+func LoadCookies(path string) ([]*http.Cookie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseJSONCookies(trimmed)
+	}
+	return ParseNetscapeCookies(bytes.NewReader(data))
+}
+
+func parseJSONCookies(data []byte) ([]*http.Cookie, error) {
+	var list []jsonCookie
+	if data[0] == '{' {
+		var state struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("parse JSON cookies: %w", err)
+		}
+		list = state.Cookies
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse JSON cookies: %w", err)
+	}
+
+	out := make([]*http.Cookie, 0, len(list))
+	for _, jc := range list {
+		c := &http.Cookie{
+			Name:     jc.Name,
+			Value:    jc.Value,
+			Domain:   jc.Domain,
+			Path:     jc.Path,
+			Secure:   jc.Secure,
+			HttpOnly: jc.HTTPOnly,
+		}
+		exp := jc.ExpirationDate
+		if exp == 0 {
+			exp = jc.Expires
+		}
+		if exp > 0 {
+			c.Expires = time.Unix(int64(exp), 0)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// ParseNetscapeCookies lit un cookies.txt au format Netscape (celui de
+// yt-dlp, curl et des extensions "Get cookies.txt").
+func ParseNetscapeCookies(r io.Reader) ([]*http.Cookie, error) {
+	var out []*http.Cookie
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			return nil, fmt.Errorf("cookies.txt line %d: want 7 tab-separated fields, got %d", n, len(f))
+		}
+		c := &http.Cookie{
+			Domain:   f[0],
+			Path:     f[2],
+			Secure:   strings.EqualFold(f[3], "TRUE"),
+			Name:     f[5],
+			Value:    f[6],
+			HttpOnly: httpOnly,
+		}
+		if exp, err := strconv.ParseInt(f[4], 10, 64); err == nil && exp > 0 {
+			c.Expires = time.Unix(exp, 0)
+		}
+		out = append(out, c)
+	}
+	return out, sc.Err()
+}
+
+// WriteNetscapeCookies écrit cookies au format Netscape, lisible par yt-dlp
+// (--cookies).
+func WriteNetscapeCookies(w io.Writer, cookies []*http.Cookie) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Netscape HTTP Cookie File")
+	for _, c := range cookies {
+		domain := c.Domain
+		if domain == "" {
+			domain = strings.TrimPrefix(BaseURL, "https://")
+		}
+		prefix := ""
+		if c.HttpOnly {
+			prefix = "#HttpOnly_"
+		}
+		path := c.Path
+		if path == "" {
+			path = "/"
+		}
+		var exp int64
+		if !c.Expires.IsZero() {
+			exp = c.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			prefix, domain, boolField(strings.HasPrefix(domain, ".")), path,
+			boolField(c.Secure), exp, c.Name, c.Value)
+	}
+	return bw.Flush()
+}
+
+func boolField(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// SaveCookies écrit cookies en JSON dans path (fichier de session, relu par
+// LoadCookies). Le fichier n'est lisible que par son propriétaire.
+func SaveCookies(path string, cookies []*http.Cookie) error {
+	list := make([]jsonCookie, 0, len(cookies))
+	for _, c := range cookies {
+		jc := jsonCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		if !c.Expires.IsZero() {
+			jc.ExpirationDate = float64(c.Expires.Unix())
+		}
+		list = append(list, jc)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// SkoolCookies ne garde que les cookies du domaine skool.com.
+func SkoolCookies(cookies []*http.Cookie) []*http.Cookie {
+	var out []*http.Cookie
+	for _, c := range cookies {
+		d := strings.TrimPrefix(c.Domain, ".")
+		if d == "" || d == "skool.com" || strings.HasSuffix(d, ".skool.com") {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// HasAuthCookie indique si cookies contient un AuthCookie non expiré.
+func HasAuthCookie(cookies []*http.Cookie) bool {
+	for _, c := range cookies {
+		if c.Name == AuthCookie && c.Value != "" && (c.Expires.IsZero() || c.Expires.After(time.Now())) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package skool
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cookieLine écrit une ligne cookies.txt à partir de ses 7 champs.
+func cookieLine(fields ...string) string {
+	return strings.Join(fields, "\t") + "\n"
+}
+
+func TestParseNetscapeCookies(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input string
+		want  []*http.Cookie
+	}{
+		{
+			name:  "plain",
+			input: cookieLine(".skool.com", "TRUE", "/", "TRUE", "1900000000", "auth_token", "abc"),
+			want:  []*http.Cookie{{Domain: ".skool.com", Path: "/", Secure: true, Name: "auth_token", Value: "abc", Expires: time.Unix(1900000000, 0)}},
+		},
+		{
+			name:  "HttpOnly prefix",
+			input: "#HttpOnly_" + cookieLine("www.skool.com", "FALSE", "/api", "FALSE", "1900000000", "sid", "x=y"),
+			want:  []*http.Cookie{{Domain: "www.skool.com", Path: "/api", Name: "sid", Value: "x=y", HttpOnly: true, Expires: time.Unix(1900000000, 0)}},
+		},
+		{
+			name:  "session cookie",
+			input: cookieLine(".skool.com", "TRUE", "/", "false", "0", "s", "1"),
+			want:  []*http.Cookie{{Domain: ".skool.com", Path: "/", Name: "s", Value: "1"}},
+		},
+		{
+			name: "comments, blank lines and CRLF",
+			input: "# Netscape HTTP Cookie File\r\n\r\n   \n# a comment\n" +
+				strings.TrimSuffix(cookieLine(".skool.com", "TRUE", "/", "FALSE", "0", "a", "1"), "\n") + "\r\n",
+			want: []*http.Cookie{{Domain: ".skool.com", Path: "/", Name: "a", Value: "1"}},
+		},
+		{
+			name:  "empty value",
+			input: cookieLine(".skool.com", "TRUE", "/", "FALSE", "0", "empty", ""),
+			want:  []*http.Cookie{{Domain: ".skool.com", Path: "/", Name: "empty"}},
+		},
+		{name: "empty file", input: "", want: nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNetscapeCookies(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNetscapeCookiesMalformed(t *testing.T) {
+	for name, input := range map[string]string{
+		"spaces instead of tabs": ".skool.com TRUE / FALSE 0 a 1\n",
+		"missing field":          "# ok\n.skool.com\tTRUE\t/\tFALSE\t0\ta\n",
+		"extra field":            cookieLine(".skool.com", "TRUE", "/", "FALSE", "0", "a", "1", "2"),
+	} {
+		if _, err := ParseNetscapeCookies(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestLoadCookies(t *testing.T) {
+	for _, tt := range []struct {
+		name, data string
+		want       []*http.Cookie
+	}{
+		{
+			name: "JSON array",
+			data: `[{"name":"auth_token","value":"abc","domain":".skool.com","path":"/","secure":true,"httpOnly":true,"expirationDate":1900000000.5}]`,
+			want: []*http.Cookie{{Name: "auth_token", Value: "abc", Domain: ".skool.com", Path: "/", Secure: true, HttpOnly: true, Expires: time.Unix(1900000000, 0)}},
+		},
+		{
+			name: "storageState object",
+			data: ` {"cookies":[{"name":"a","value":"1","domain":"www.skool.com","path":"/","expires":-1}],"origins":[]}`,
+			want: []*http.Cookie{{Name: "a", Value: "1", Domain: "www.skool.com", Path: "/"}},
+		},
+		{
+			name: "JSON session cookie",
+			data: `[{"name":"s","value":"1","domain":".skool.com","path":"/"}]`,
+			want: []*http.Cookie{{Name: "s", Value: "1", Domain: ".skool.com", Path: "/"}},
+		},
+		{
+			name: "Netscape",
+			data: "# Netscape HTTP Cookie File\n" + cookieLine(".skool.com", "TRUE", "/", "FALSE", "0", "a", "1"),
+			want: []*http.Cookie{{Domain: ".skool.com", Path: "/", Name: "a", Value: "1"}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cookies")
+			os.WriteFile(path, []byte(tt.data), 0o600)
+			got, err := LoadCookies(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(path, []byte(`[{"name":`), 0o600)
+	if _, err := LoadCookies(path); err == nil {
+		t.Error("LoadCookies accepted truncated JSON")
+	}
+}
+
+func TestCookiesRoundTrip(t *testing.T) {
+	cookies := []*http.Cookie{
+		{Name: "auth_token", Value: "abc", Domain: ".skool.com", Path: "/", Secure: true, HttpOnly: true, Expires: time.Unix(1900000000, 0)},
+		{Name: "s", Value: "1", Domain: "www.skool.com", Path: "/"},
+	}
+	var buf bytes.Buffer
+	if err := WriteNetscapeCookies(&buf, cookies); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseNetscapeCookies(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cookies) {
+		t.Errorf("Netscape round trip = %+v, want %+v", got, cookies)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := SaveCookies(path, cookies); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = LoadCookies(path); err != nil || !reflect.DeepEqual(got, cookies) {
+		t.Errorf("JSON round trip = %+v, %v, want %+v", got, err, cookies)
+	}
+}
+
+func TestHasAuthCookie(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		cookies []*http.Cookie
+		want    bool
+	}{
+		{"session cookie", []*http.Cookie{{Name: AuthCookie, Value: "x"}}, true},
+		{"future expiry", []*http.Cookie{{Name: AuthCookie, Value: "x", Expires: time.Now().Add(time.Hour)}}, true},
+		{"expired", []*http.Cookie{{Name: AuthCookie, Value: "x", Expires: time.Now().Add(-time.Hour)}}, false},
+		{"empty value", []*http.Cookie{{Name: AuthCookie}}, false},
+		{"other cookie", []*http.Cookie{{Name: "sid", Value: "x"}}, false},
+	} {
+		if got := HasAuthCookie(tt.cookies); got != tt.want {
+			t.Errorf("%s: HasAuthCookie = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
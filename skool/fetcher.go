@@ -0,0 +1,119 @@
+package skool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// PageFetcher retourne le JSON brut du <script id="__NEXT_DATA__"> d'une page
+// Skool. ready (optionnel) dit si ce JSON contient les props attendues.
+type PageFetcher interface {
+	NextData(ctx context.Context, pageURL string, ready ReadyFunc) (string, error)
+}
+
+// ReadyFunc indique si le JSON __NEXT_DATA__ lu est celui attendu (ex.
+// pageProps.course avec le bon id). nil accepte toute page.
+type ReadyFunc func(raw string) bool
+
+var (
+	// ErrNoNextData est retourné quand la page ne contient pas de
+	// __NEXT_DATA__ (page d'erreur, etc.).
+	ErrNoNextData = errors.New("no __NEXT_DATA__ in page")
+	// ErrPageNotReady est retourné quand __NEXT_DATA__ n'a jamais contenu
+	// les props attendues.
+	ErrPageNotReady = errors.New("page not ready")
+)
+
+// maxPageSize borne la taille d'une page lue par HTTPFetcher.
+const maxPageSize = 32 << 20
+
+// -----------------------------------------------------------------------------
+// HTTPFetcher => GET + cookie de session, sans navigateur
+// -----------------------------------------------------------------------------
+
+// HTTPFetcher lit #__NEXT_DATA__ avec de simples requêtes HTTP. Skool rend
+// ses pages côté serveur : avec un cookie de session valide, le HTML contient
+// déjà tout le JSON, sans Chrome.
+type HTTPFetcher struct {
+	Client *http.Client
+}
+
+// NewHTTPFetcher retourne un fetcher HTTP dont le cookie jar contient
+// cookies (au minimum AuthCookie pour les classrooms privées).
+func NewHTTPFetcher(cookies []*http.Cookie) *HTTPFetcher {
+	c := NewHTTPClient(cookies)
+	c.Timeout = 60 * time.Second
+	return &HTTPFetcher{Client: c}
+}
+
+// NewHTTPClient retourne un client HTTP dont le cookie jar contient cookies
+// pour skool.com ; les cookies de domaine (".skool.com") suivent aussi sur
+// les sous-domaines (fichiers, CDN).
+func NewHTTPClient(cookies []*http.Cookie) *http.Client {
+	jar, _ := cookiejar.New(nil)
+	base, _ := url.Parse(BaseURL)
+	jar.SetCookies(base, cookies)
+	return &http.Client{Jar: jar}
+}
+
+// Cookies retourne les cookies skool.com actuellement dans le jar.
+func (f *HTTPFetcher) Cookies() []*http.Cookie {
+	if f.Client.Jar == nil {
+		return nil
+	}
+	base, _ := url.Parse(BaseURL)
+	return f.Client.Jar.Cookies(base)
+}
+
+// NextData implémente PageFetcher. La page étant rendue côté serveur, il n'y
+// a rien à attendre : un JSON refusé par ready donne ErrPageNotReady.
+func (f *HTTPFetcher) NextData(ctx context.Context, pageURL string, ready ReadyFunc) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if isLoginURL(resp.Request.URL) {
+		return "", fmt.Errorf("GET %s: %w", pageURL, ErrNotLoggedIn)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", pageURL, resp.Status)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return "", err
+	}
+	raw, err := ExtractNextData(page)
+	if err != nil {
+		return "", fmt.Errorf("GET %s: %w", pageURL, err)
+	}
+	if ready != nil && !ready(raw) {
+		return "", fmt.Errorf("GET %s: %w", pageURL, ErrPageNotReady)
+	}
+	return raw, nil
+}
+
+var reNextData = regexp.MustCompile(`(?s)<script[^>]*\bid="__NEXT_DATA__"[^>]*>(.*?)</script>`)
+
+// ExtractNextData retourne le contenu du <script id="__NEXT_DATA__"> de page.
+func ExtractNextData(page []byte) (string, error) {
+	m := reNextData.FindSubmatch(page)
+	if m == nil {
+		return "", ErrNoNextData
+	}
+	return string(m[1]), nil
+}
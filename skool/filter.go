@@ -0,0 +1,249 @@
+package skool
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Filter => sélection des cours / modules avant toute navigation
+// -----------------------------------------------------------------------------
+
+// Filter sélectionne les cours et modules à traiter. Un motif est :
+//
+//   - "course:<motif>" : ne s'applique qu'aux cours (titre, slug ou ID) ;
+//   - "module:<motif>" : ne s'applique qu'aux modules (titre ou ID) ;
+//   - sans préfixe : s'applique aux deux ; s'il ne vise aucun cours de la
+//     classroom (voir ResolveCourses), il ne s'applique qu'aux modules.
+//
+// <motif> est un glob insensible à la casse ("*intro*") ou une regexp entre
+// slashs ("/^week [0-9]+/"). Un ID ou un slug se compare tel quel.
+//
+// Un élément est exclu dès qu'un motif Exclude le vise. Si des motifs Include
+// existent, un cours n'est gardé que si l'un d'eux le vise ou si un motif
+// "module:" existe (il peut viser les modules de n'importe quel cours) ; un
+// module n'est gardé que si son cours ou lui-même est visé.
+// Modules restreint en plus les modules à des positions (1-based) parmi les
+// leçons du cours, sets dépliés (voir Lessons).
+// Un Filter nil garde tout.
+type Filter struct {
+	include []pattern
+	exclude []pattern
+	ranges  [][2]int
+}
+
+type patternScope int
+
+const (
+	scopeBoth patternScope = iota
+	scopeCourse
+	scopeModule
+)
+
+type pattern struct {
+	scope patternScope
+	raw   string
+	re    *regexp.Regexp
+}
+
+// NewFilter compile les motifs include / exclude et les plages modules
+// ("3-7,12"). Il retourne nil si aucun critère n'est donné.
+func NewFilter(include, exclude []string, modules string) (*Filter, error) {
+	f := &Filter{}
+	for _, s := range include {
+		p, err := parsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, p)
+	}
+	for _, s := range exclude {
+		p, err := parsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, p)
+	}
+	ranges, err := ParseRanges(modules)
+	if err != nil {
+		return nil, err
+	}
+	f.ranges = ranges
+	if len(f.include) == 0 && len(f.exclude) == 0 && len(f.ranges) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func parsePattern(s string) (pattern, error) {
+	p := pattern{scope: scopeBoth}
+	switch {
+	case strings.HasPrefix(s, "course:"):
+		p.scope, s = scopeCourse, strings.TrimPrefix(s, "course:")
+	case strings.HasPrefix(s, "module:"):
+		p.scope, s = scopeModule, strings.TrimPrefix(s, "module:")
+	}
+	p.raw = s
+	expr := globToRegexp(s)
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		expr = "(?i)" + s[1:len(s)-1]
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return p, fmt.Errorf("invalid pattern %q: %w", s, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+// globToRegexp traduit un glob (* et ?) en regexp ancrée, insensible à la casse.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("(?i)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+func (p pattern) match(values ...string) bool {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		if v == p.raw || p.re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p pattern) matchCourse(c Course) bool {
+	return p.scope != scopeModule && p.match(c.Title, CourseSlug(c.URL), c.ID)
+}
+
+func (p pattern) matchModule(m ModuleInfo) bool {
+	return p.scope != scopeCourse && p.match(m.Title, m.ID)
+}
+
+// CourseSlug retourne le slug d'un cours (dernier segment de son URL).
+func CourseSlug(courseURL string) string {
+	u, err := url.Parse(courseURL)
+	if err != nil {
+		return ""
+	}
+	return path.Base(strings.TrimRight(u.Path, "/"))
+}
+
+// ParseRanges lit une liste de positions 1-based : "3-7,12".
+func ParseRanges(s string) ([][2]int, error) {
+	var out [][2]int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		a, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil || a < 1 {
+			return nil, fmt.Errorf("invalid module range %q", part)
+		}
+		b := a
+		if isRange {
+			if b, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil || b < a {
+				return nil, fmt.Errorf("invalid module range %q", part)
+			}
+		}
+		out = append(out, [2]int{a, b})
+	}
+	return out, nil
+}
+
+// ResolveCourses adapte les motifs Include sans préfixe à la liste des cours
+// de la classroom : un motif qui ne vise aucun d'eux (ex. l'ID d'un module)
+// est traité comme un motif "module:". Il retourne ces motifs, pour les
+// signaler.
+func (f *Filter) ResolveCourses(courses []Course) []string {
+	if f == nil {
+		return nil
+	}
+	var moved []string
+	for i, p := range f.include {
+		if p.scope != scopeBoth || slices.ContainsFunc(courses, p.matchCourse) {
+			continue
+		}
+		f.include[i].scope = scopeModule
+		moved = append(moved, p.raw)
+	}
+	return moved
+}
+
+// KeepCourse indique si le cours c doit être traité.
+func (f *Filter) KeepCourse(c Course) bool {
+	if f == nil {
+		return true
+	}
+	for _, p := range f.exclude {
+		if p.matchCourse(c) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		// Un motif "module:" peut viser un module de n'importe quel cours ;
+		// un motif sans préfixe ne garde que les cours qu'il vise.
+		if p.scope == scopeModule || p.matchCourse(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepModule indique si la leçon m, en position idx (0-based) parmi les
+// leçons du cours c, doit être traitée.
+func (f *Filter) KeepModule(c Course, idx int, m ModuleInfo) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.ranges) > 0 && !inRanges(f.ranges, idx+1) {
+		return false
+	}
+	for _, p := range f.exclude {
+		if p.matchModule(m) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.matchCourse(c) || p.matchModule(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func inRanges(ranges [][2]int, n int) bool {
+	for _, r := range ranges {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+	return false
+}
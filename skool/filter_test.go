@@ -0,0 +1,120 @@
+package skool
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	intro = Course{ID: "c1", Title: "Intro to AI", URL: "https://www.skool.com/g/classroom/ai-basics"}
+	sales = Course{ID: "c2", Title: "Sales Mastery", URL: "https://www.skool.com/g/classroom/sales"}
+
+	welcome = ModuleInfo{ID: "m1", Title: "Welcome"}
+	draft   = ModuleInfo{ID: "m2", Title: "Draft: pricing"}
+	week3   = ModuleInfo{ID: "abc123", Title: "Week 3"}
+)
+
+func TestKeepCourse(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		include, exclude []string
+		want             []bool // intro, sales
+	}{
+		{"no filter", nil, nil, []bool{true, true}},
+		{"glob on title", []string{"*intro*"}, nil, []bool{true, false}},
+		{"slug", []string{"sales"}, nil, []bool{false, true}},
+		{"course ID", []string{"course:c1"}, nil, []bool{true, false}},
+		{"regexp", []string{"/mastery$/"}, nil, []bool{false, true}},
+		{"module pattern keeps every course", []string{"module:welcome"}, nil, []bool{true, true}},
+		{"exclude wins", []string{"*"}, []string{"course:ai-basics"}, []bool{false, true}},
+		{"module exclude keeps the course", nil, []string{"module:*"}, []bool{true, true}},
+		{"unprefixed module ID", []string{"abc123"}, nil, []bool{true, true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.include, tt.exclude, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.ResolveCourses([]Course{intro, sales})
+			got := []bool{f.KeepCourse(intro), f.KeepCourse(sales)}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeepCourse = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepModule(t *testing.T) {
+	mods := []ModuleInfo{welcome, draft, week3}
+	for _, tt := range []struct {
+		name             string
+		include, exclude []string
+		ranges           string
+		want             []bool // welcome, draft, week3 in intro
+	}{
+		{"no filter", nil, nil, "", []bool{true, true, true}},
+		{"selected course keeps its modules", []string{"course:c1"}, nil, "", []bool{true, true, true}},
+		{"other course", []string{"course:sales"}, nil, "", []bool{false, false, false}},
+		{"module glob", []string{"module:w*"}, nil, "", []bool{true, false, true}},
+		{"unprefixed module ID", []string{"abc123"}, nil, "", []bool{false, false, true}},
+		{"exclude regexp", nil, []string{"module:/^draft/"}, "", []bool{true, false, true}},
+		{"course exclude ignores modules", nil, []string{"course:welcome"}, "", []bool{true, true, true}},
+		{"ranges", nil, nil, "1,3", []bool{true, false, true}},
+		{"ranges and include", []string{"module:*r*"}, nil, "2-3", []bool{false, true, false}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.include, tt.exclude, tt.ranges)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.ResolveCourses([]Course{intro, sales})
+			var got []bool
+			for i, m := range mods {
+				got = append(got, f.KeepModule(intro, i, m))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeepModule = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCourses(t *testing.T) {
+	f, err := NewFilter([]string{"*intro*", "abc123", "course:nothing", "module:m1"}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.ResolveCourses([]Course{intro, sales}); !reflect.DeepEqual(got, []string{"abc123"}) {
+		t.Errorf("ResolveCourses = %q, want [abc123]", got)
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	for s, want := range map[string][][2]int{
+		"":           nil,
+		"3":          {{3, 3}},
+		"3-7,12":     {{3, 7}, {12, 12}},
+		" 1 - 2 , 5": {{1, 2}, {5, 5}},
+		"4,,":        {{4, 4}},
+	} {
+		got, err := ParseRanges(s)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseRanges(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"0", "x", "7-3", "2-", "-1"} {
+		if _, err := ParseRanges(s); err == nil {
+			t.Errorf("ParseRanges(%q) succeeded", s)
+		}
+	}
+}
+
+func TestNewFilterEmpty(t *testing.T) {
+	f, err := NewFilter(nil, nil, "")
+	if f != nil || err != nil {
+		t.Errorf("NewFilter() = %v, %v, want nil, nil", f, err)
+	}
+	if _, err := NewFilter([]string{"/[/"}, nil, ""); err == nil {
+		t.Error("NewFilter accepted an invalid regexp")
+	}
+}
@@ -0,0 +1,107 @@
+package skool
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Resources => fichiers et liens joints à une leçon
+// -----------------------------------------------------------------------------
+
+// Resource est une ressource jointe à une leçon (PDF, modèle, lien...).
+type Resource struct {
+	Title string
+	URL   string
+	// FileName est le nom d'origine du fichier ; "" pour un simple lien.
+	FileName    string
+	ContentType string
+}
+
+// IsFile indique si la ressource est un fichier à télécharger plutôt qu'un
+// lien vers une page.
+func (r Resource) IsFile() bool {
+	return r.FileName != ""
+}
+
+// resourceKeys sont les clés de metadata qui listent les ressources. Skool
+// les stocke souvent sous forme de JSON dans une chaîne.
+var resourceKeys = []string{"resources", "attachments", "files"}
+
+// ExtractResources retourne les ressources déclarées dans metadata du nœud
+// "course" d'une leçon, dans l'ordre.
+func ExtractResources(course map[string]interface{}) []Resource {
+	metadata, _ := course["metadata"].(map[string]interface{})
+	var out []Resource
+	seen := map[string]bool{}
+	for _, k := range resourceKeys {
+		for _, item := range resourceItems(metadata[k]) {
+			r, ok := parseResource(item)
+			if !ok || seen[r.URL] {
+				continue
+			}
+			seen[r.URL] = true
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// resourceItems retourne les entrées de v : tableau JSON, ou chaîne le
+// contenant.
+func resourceItems(v interface{}) []map[string]interface{} {
+	if s, ok := v.(string); ok {
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil
+		}
+	}
+	arr, _ := v.([]interface{})
+	var out []map[string]interface{}
+	for _, it := range arr {
+		if m, ok := it.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func parseResource(item map[string]interface{}) (Resource, bool) {
+	str := func(keys ...string) string {
+		for _, k := range keys {
+			if s, ok := item[k].(string); ok && strings.TrimSpace(s) != "" {
+				return strings.TrimSpace(s)
+			}
+		}
+		return ""
+	}
+	r := Resource{
+		URL:         str("link", "url", "file_url", "fileUrl", "download_url", "downloadUrl", "src"),
+		FileName:    str("file_name", "fileName", "filename"),
+		ContentType: str("content_type", "contentType", "file_content_type", "mime_type", "mimeType"),
+	}
+	if r.URL == "" {
+		return r, false
+	}
+	if r.FileName == "" {
+		r.FileName = fileNameFromURL(r.URL)
+	}
+	r.Title = firstNonEmpty(str("title", "label", "name"), r.FileName, r.URL)
+	return r, true
+}
+
+// fileNameFromURL retourne le dernier segment de u s'il désigne un fichier
+// (extension autre qu'une page web), "" sinon.
+func fileNameFromURL(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	base := path.Base(pu.Path)
+	switch strings.ToLower(path.Ext(base)) {
+	case "", ".html", ".htm", ".php", ".asp", ".aspx":
+		return ""
+	}
+	return base
+}
@@ -0,0 +1,406 @@
+// Package skool lit les pages Skool (classroom, cours, modules) et expose leur
+// contenu sous forme de types Go. Les pages sont lues via un PageFetcher :
+// navigateur chromedp ou simple HTTP.
+package skool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+// Constantes + Types
+// -----------------------------------------------------------------------------
+const (
+	// BrowserTimeout borne la durée de vie totale du navigateur.
+	BrowserTimeout = 1800 * time.Second
+	// BaseURL est l'origine du site Skool.
+	BaseURL = "https://www.skool.com"
+	// LoginURL est la page de connexion Skool.
+	LoginURL = BaseURL + "/login"
+	// AuthCookie est le cookie de session posé par Skool après connexion.
+	AuthCookie = "auth_token"
+
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 " +
+		"(KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36"
+)
+
+// Course est un cours d'une classroom.
+type Course struct {
+	ID    string
+	Title string
+	URL   string
+	// Index est la position (1-based) du cours dans la classroom ; 0 si
+	// inconnue (URL d'un cours seul).
+	Index int
+}
+
+// ModuleInfo est un module d'un cours : une leçon, ou un set (dossier) qui
+// regroupe des leçons.
+type ModuleInfo struct {
+	ID    string
+	Title string
+	URL   string
+	// Index est la position (1-based) du module dans son cours ou son set.
+	Index int
+	// UpdatedAt est la date de dernière modification annoncée par Skool.
+	UpdatedAt string
+	// Hash résume le contenu du module (updatedAt + metadata) : il change
+	// dès que le créateur modifie la leçon.
+	Hash string
+	// ParentID est l'ID du set (dossier) qui contient le module, "" au
+	// premier niveau du cours.
+	ParentID string
+	// Set indique un set : il regroupe les modules de Children et n'a pas de
+	// contenu propre.
+	Set      bool
+	Children []ModuleInfo
+}
+
+// Lessons retourne les leçons de mods (les modules qui ne sont pas des sets),
+// sets dépliés, dans l'ordre du cours.
+func Lessons(mods []ModuleInfo) []ModuleInfo {
+	var out []ModuleInfo
+	for _, m := range mods {
+		if m.Set {
+			out = append(out, Lessons(m.Children)...)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// Lesson est le contenu brut d'un module : description Tiptap, liens vidéo et
+// ressources jointes trouvés dans le JSON du module.
+type Lesson struct {
+	Description string
+	VideoLinks  []string
+	Resources   []Resource
+}
+
+// Client lit les pages Skool via un PageFetcher.
+type Client struct {
+	Fetcher PageFetcher
+}
+
+// NewClient retourne un Client qui lit les pages avec f.
+func NewClient(f PageFetcher) *Client {
+	return &Client{Fetcher: f}
+}
+
+// -----------------------------------------------------------------------------
+// Courses => lit __NEXT_DATA__ => pageProps.allCourses
+// -----------------------------------------------------------------------------
+
+// Courses liste les cours de la classroom skoolURL. Si skoolURL pointe vers un
+// cours unique, il est retourné seul.
+func (c *Client) Courses(ctx context.Context, skoolURL string) ([]Course, error) {
+	raw, err := c.Fetcher.NextData(ctx, skoolURL, classroomReady)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try to parse as a page listing all courses
+	var multi struct {
+		Props struct {
+			PageProps struct {
+				AllCourses []struct {
+					ID       string `json:"id"`
+					Name     string `json:"name"`
+					Metadata struct {
+						Title string `json:"title"`
+					} `json:"metadata"`
+				} `json:"allCourses"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+	if e := json.Unmarshal([]byte(raw), &multi); e != nil {
+		return nil, e
+	}
+
+	// If the page contains "allCourses" we return them
+	if len(multi.Props.PageProps.AllCourses) > 0 {
+		var out []Course
+		for i, c := range multi.Props.PageProps.AllCourses {
+			title := strings.TrimSpace(c.Metadata.Title)
+			url := strings.TrimRight(skoolURL, "/") + "/" + c.Name
+			out = append(out, Course{ID: c.ID, Title: title, URL: url, Index: i + 1})
+		}
+		return out, nil
+	}
+
+	// Otherwise we treat the provided URL as a single course
+	var single struct {
+		Props struct {
+			PageProps struct {
+				Course struct {
+					ID       string `json:"id"`
+					Metadata struct {
+						Title string `json:"title"`
+					} `json:"metadata"`
+					// Selon les pages, le cours est imbriqué dans son nœud.
+					Course struct {
+						ID       string `json:"id"`
+						Metadata struct {
+							Title string `json:"title"`
+						} `json:"metadata"`
+					} `json:"course"`
+				} `json:"course"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+	if e := json.Unmarshal([]byte(raw), &single); e != nil {
+		return nil, e
+	}
+
+	sc := single.Props.PageProps.Course
+	id := firstNonEmpty(sc.ID, sc.Course.ID)
+	title := firstNonEmpty(strings.TrimSpace(sc.Metadata.Title), strings.TrimSpace(sc.Course.Metadata.Title))
+	if title == "" {
+		title = "Course"
+	}
+	return []Course{{ID: id, Title: title, URL: skoolURL}}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Modules => arbre children => ID + title
+// -----------------------------------------------------------------------------
+
+// moduleNode est un nœud de pageProps.course.children : set ou leçon.
+type moduleNode struct {
+	Course struct {
+		ID        string          `json:"id"`
+		UnitType  string          `json:"unitType"`
+		UpdatedAt string          `json:"updatedAt"`
+		Metadata  json.RawMessage `json:"metadata"`
+	} `json:"course"`
+	Children []moduleNode `json:"children"`
+}
+
+// Modules retourne l'arbre des modules du cours courseURL : les sets
+// (dossiers) portent leurs leçons dans Children, à toute profondeur. Voir
+// Lessons pour la liste à plat.
+func (c *Client) Modules(ctx context.Context, courseURL string) ([]ModuleInfo, error) {
+	raw, err := c.Fetcher.NextData(ctx, courseURL, courseReady)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Props struct {
+			PageProps struct {
+				Course struct {
+					Children []moduleNode `json:"children"`
+				} `json:"course"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+	if e := json.Unmarshal([]byte(raw), &data); e != nil {
+		return nil, e
+	}
+	return moduleTree(courseURL, "", data.Props.PageProps.Course.Children), nil
+}
+
+func moduleTree(courseURL, parentID string, nodes []moduleNode) []ModuleInfo {
+	var ms []ModuleInfo
+	for i, n := range nodes {
+		id := n.Course.ID
+		var md struct {
+			Title string `json:"title"`
+		}
+		_ = json.Unmarshal(n.Course.Metadata, &md)
+		t := strings.TrimSpace(md.Title)
+		if t == "" {
+			t = "Untitled"
+		}
+		m := ModuleInfo{
+			ID:        id,
+			Title:     t,
+			URL:       courseURL + "?md=" + id,
+			Index:     i + 1,
+			UpdatedAt: n.Course.UpdatedAt,
+			Hash:      contentHash(n.Course.UpdatedAt, n.Course.Metadata),
+			ParentID:  parentID,
+			Set:       n.Course.UnitType == "set" || len(n.Children) > 0,
+		}
+		m.Children = moduleTree(courseURL, id, n.Children)
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+// -----------------------------------------------------------------------------
+// Lesson => description + videoLink du module
+// -----------------------------------------------------------------------------
+
+// Lesson lit la page du module m et retourne sa description, ses liens vidéo
+// et ses ressources.
+func (c *Client) Lesson(ctx context.Context, m ModuleInfo) (Lesson, error) {
+	raw, err := c.Fetcher.NextData(ctx, m.URL, moduleReady(m.ID))
+	if err != nil {
+		return Lesson{}, err
+	}
+
+	var data struct {
+		Props struct {
+			PageProps struct {
+				Course struct {
+					Children []interface{} `json:"children"`
+				} `json:"course"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return Lesson{}, err
+	}
+
+	var l Lesson
+	course := findModule(data.Props.PageProps.Course.Children, m.ID)
+	if course == nil {
+		return l, nil
+	}
+	// Description (desc)
+	metadata, _ := course["metadata"].(map[string]interface{})
+	if metadata != nil {
+		if d, ok := metadata["desc"].(string); ok {
+			l.Description = d
+		}
+	}
+	// Recherche récursive de tous les videoLink dans la structure complète du module
+	l.VideoLinks = ExtractAllVideoLinks(course)
+	l.Resources = ExtractResources(course)
+	return l, nil
+}
+
+// findModule cherche, à toute profondeur de children, le nœud "course" d'ID id.
+func findModule(children []interface{}, id string) map[string]interface{} {
+	for _, c := range children {
+		ch, _ := c.(map[string]interface{})
+		if ch == nil {
+			continue
+		}
+		if course, _ := ch["course"].(map[string]interface{}); course != nil {
+			if cid, _ := course["id"].(string); cid == id {
+				return course
+			}
+		}
+		sub, _ := ch["children"].([]interface{})
+		if found := findModule(sub, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// contentHash résume la version d'un module.
+func contentHash(updatedAt string, metadata []byte) string {
+	h := sha256.New()
+	h.Write([]byte(updatedAt))
+	h.Write([]byte{0})
+	h.Write(metadata)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// -----------------------------------------------------------------------------
+// Readiness => props attendues dans __NEXT_DATA__
+// -----------------------------------------------------------------------------
+
+// courseNode est un nœud de l'arbre pageProps.course réduit à ce qui sert à
+// reconnaître la page.
+type courseNode struct {
+	Course struct {
+		ID string `json:"id"`
+	} `json:"course"`
+	Children []courseNode `json:"children"`
+}
+
+type readyProps struct {
+	Props struct {
+		PageProps struct {
+			AllCourses []json.RawMessage `json:"allCourses"`
+			Course     *courseNode       `json:"course"`
+		} `json:"pageProps"`
+	} `json:"props"`
+}
+
+func parseReadyProps(raw string) (readyProps, bool) {
+	var p readyProps
+	return p, json.Unmarshal([]byte(raw), &p) == nil
+}
+
+// classroomReady accepte une classroom (allCourses) ou un cours seul.
+func classroomReady(raw string) bool {
+	p, ok := parseReadyProps(raw)
+	return ok && (len(p.Props.PageProps.AllCourses) > 0 || p.Props.PageProps.Course != nil)
+}
+
+// courseReady accepte une page de cours (pageProps.course).
+func courseReady(raw string) bool {
+	p, ok := parseReadyProps(raw)
+	return ok && p.Props.PageProps.Course != nil
+}
+
+// moduleReady accepte une page de cours contenant le module id.
+func moduleReady(id string) ReadyFunc {
+	return func(raw string) bool {
+		p, ok := parseReadyProps(raw)
+		return ok && p.Props.PageProps.Course != nil && p.Props.PageProps.Course.has(id)
+	}
+}
+
+func (n *courseNode) has(id string) bool {
+	for i := range n.Children {
+		if n.Children[i].Course.ID == id || n.Children[i].has(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+// Recherche récursive de tous les videoLink dans la structure
+// -----------------------------------------------------------------------------
+
+// ExtractAllVideoLinks retourne toutes les valeurs "videoLink" trouvées dans
+// val, quelle que soit leur profondeur.
+func ExtractAllVideoLinks(val interface{}) []string {
+	var links []string
+	switch v := val.(type) {
+	case map[string]interface{}:
+		// Clés triées : l'ordre (donc la numérotation des vidéos) reste
+		// stable d'un run à l'autre.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := v[k]
+			if k == "videoLink" {
+				if link, ok := child.(string); ok && link != "" {
+					links = append(links, link)
+				}
+			}
+			links = append(links, ExtractAllVideoLinks(child)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			links = append(links, ExtractAllVideoLinks(item)...)
+		}
+	}
+	return links
+}
@@ -0,0 +1,334 @@
+package tiptap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Tiptap -> Markdown (CommonMark + tables / task lists GFM)
+// -----------------------------------------------------------------------------
+
+// DescriptionMarkdown convertit une description Skool en Markdown. Le texte
+// brut (ou un JSON illisible) est rendu tel quel, échappé.
+func DescriptionMarkdown(desc string) string {
+	return Renderer{}.DescriptionMarkdown(desc)
+}
+
+// RenderMarkdown rend une suite de nœuds en Markdown.
+func RenderMarkdown(nodes []Node) string {
+	return Renderer{}.RenderMarkdown(nodes)
+}
+
+// DescriptionMarkdown est la fonction DescriptionMarkdown avec les options
+// de r.
+func (r Renderer) DescriptionMarkdown(desc string) string {
+	if desc == "" {
+		return ""
+	}
+	desc = strings.ReplaceAll(desc, "[v2]", "")
+	if nodes, ok := Parse(desc); ok {
+		return strings.TrimSpace(r.RenderMarkdown(nodes))
+	}
+	return mdEscape(strings.TrimSpace(desc))
+}
+
+// RenderMarkdown est la fonction RenderMarkdown avec les options de r. Les
+// blocs sont séparés par une ligne vide.
+func (r Renderer) RenderMarkdown(nodes []Node) string {
+	var blocks []string
+	inline := ""
+	flush := func() {
+		if strings.TrimSpace(inline) != "" {
+			blocks = append(blocks, strings.TrimSpace(inline))
+		}
+		inline = ""
+	}
+	for _, n := range nodes {
+		if isInline(n.Type) {
+			inline += r.mdInline(n)
+			continue
+		}
+		flush()
+		if b := r.mdBlock(n); strings.TrimSpace(b) != "" {
+			blocks = append(blocks, strings.TrimRight(b, "\n"))
+		}
+	}
+	flush()
+	if len(blocks) == 0 {
+		return ""
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+func isInline(typ string) bool {
+	switch typ {
+	case "text", "hardBreak", "mention", "emoji", "image":
+		return true
+	}
+	return false
+}
+
+func (r Renderer) mdInlines(nodes []Node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		if isInline(n.Type) {
+			sb.WriteString(r.mdInline(n))
+			continue
+		}
+		// Bloc dans un contexte en ligne (cellule, titre) : rendu sur une
+		// ligne, séparé du bloc précédent par <br>.
+		b := strings.ReplaceAll(strings.TrimSpace(r.mdBlock(n)), "\n", " ")
+		if b == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("<br>")
+		}
+		sb.WriteString(b)
+	}
+	return sb.String()
+}
+
+func (r Renderer) mdBlock(node Node) string {
+	switch node.Type {
+	case "doc":
+		return r.RenderMarkdown(node.Content)
+	case "heading":
+		level := 1
+		if l, ok := node.Attrs["level"].(float64); ok && l >= 1 && l <= 6 {
+			level = int(l)
+		}
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(r.mdInlines(node.Content))
+	case "paragraph":
+		return strings.TrimSpace(r.mdInlines(node.Content))
+	case "bulletList", "taskList":
+		return r.mdList(node, false, 0)
+	case "orderedList":
+		start := 1
+		if s, ok := node.Attrs["start"].(float64); ok {
+			start = int(s)
+		}
+		return r.mdList(node, true, start)
+	case "blockquote":
+		return prefixLines(r.RenderMarkdown(node.Content), "> ", "> ")
+	case "codeBlock":
+		code := plainText(node.Content)
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		lang, _ := node.Attrs["language"].(string)
+		return fence + lang + "\n" + strings.TrimRight(code, "\n") + "\n" + fence
+	case "horizontalRule":
+		return "---"
+	case "table":
+		return r.mdTable(node)
+	case "video", "youtube", "iframe", "embed", "loom", "vimeo":
+		src := webURL(attrString(node.Attrs, "src", "url", "href"))
+		if src == "" {
+			return ""
+		}
+		return "[" + mdEscape(src) + "](" + mdURL(src) + ")"
+	}
+
+	r.unknown("node", node.Type)
+	out := "> [" + r.unsupported() + " : " + node.Type + "]"
+	if len(node.Content) > 0 {
+		out += "\n\n" + r.RenderMarkdown(node.Content)
+	}
+	return out
+}
+
+// mdList rend une liste à puces, ou numérotée à partir de start si ordered.
+func (r Renderer) mdList(node Node, ordered bool, start int) string {
+	var items []string
+	for i, item := range node.Content {
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", start+i)
+		}
+		if item.Type == "taskItem" {
+			if c, _ := item.Attrs["checked"].(bool); c {
+				marker += "[x] "
+			} else {
+				marker += "[ ] "
+			}
+		}
+		body := strings.TrimRight(r.RenderMarkdown(item.Content), "\n")
+		items = append(items, prefixLines(body, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// mdTable rend un tableau GFM ; la première ligne sert d'en-tête.
+func (r Renderer) mdTable(node Node) string {
+	var rows [][]string
+	width := 0
+	for _, row := range node.Content {
+		var cells []string
+		for _, cell := range row.Content {
+			txt := strings.TrimSpace(r.mdInlines(cell.Content))
+			cells = append(cells, strings.ReplaceAll(txt, "|", `\|`))
+		}
+		width = max(width, len(cells))
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 || width == 0 {
+		return ""
+	}
+	line := func(cells []string) string {
+		for len(cells) < width {
+			cells = append(cells, "")
+		}
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+	sep := make([]string, width)
+	for i := range sep {
+		sep[i] = "---"
+	}
+	out := []string{line(rows[0]), line(sep)}
+	for _, row := range rows[1:] {
+		out = append(out, line(row))
+	}
+	return strings.Join(out, "\n")
+}
+
+func (r Renderer) mdInline(node Node) string {
+	switch node.Type {
+	case "text":
+		return r.mdText(node)
+	case "hardBreak":
+		return "\\\n"
+	case "mention":
+		return "@" + mdEscape(attrString(node.Attrs, "label", "name", "id"))
+	case "emoji":
+		if e := attrString(node.Attrs, "emoji"); e != "" {
+			return e
+		}
+		if node.Text != "" {
+			return node.Text
+		}
+		return ":" + attrString(node.Attrs, "name") + ":"
+	case "image":
+		src, _ := node.Attrs["src"].(string)
+		if src == "" {
+			return ""
+		}
+		if r.ImageSrc != nil {
+			src = r.ImageSrc(src)
+		}
+		alt, _ := node.Attrs["alt"].(string)
+		title := ""
+		if t, _ := node.Attrs["title"].(string); t != "" {
+			title = ` "` + strings.ReplaceAll(t, `"`, `\"`) + `"`
+		}
+		return "![" + mdEscape(alt) + "](" + mdURL(src) + title + ")"
+	}
+	return ""
+}
+
+// mdText rend un nœud texte et ses marks. Les marks sans équivalent Markdown
+// (souligné, surligné...) sont rendues en HTML en ligne.
+func (r Renderer) mdText(node Node) string {
+	txt := mdEscape(node.Text)
+	for _, mark := range node.Marks {
+		if mark.Type == "code" {
+			// Le code est littéral : il passe sous les autres marks.
+			txt = mdCode(node.Text)
+		}
+	}
+	for _, mark := range node.Marks {
+		switch mark.Type {
+		case "bold":
+			txt = mdWrap(txt, "**", "**")
+		case "italic":
+			txt = mdWrap(txt, "*", "*")
+		case "strike":
+			txt = mdWrap(txt, "~~", "~~")
+		case "code":
+		case "underline":
+			txt = mdWrap(txt, "<u>", "</u>")
+		case "highlight":
+			txt = mdWrap(txt, "<mark>", "</mark>")
+		case "subscript":
+			txt = mdWrap(txt, "<sub>", "</sub>")
+		case "superscript":
+			txt = mdWrap(txt, "<sup>", "</sup>")
+		case "textStyle":
+			// Pas de couleur en Markdown : texte seul.
+		case "link":
+			if href := webURL(attrString(mark.Attrs, "href")); href != "" {
+				txt = "[" + txt + "](" + mdURL(href) + ")"
+			}
+		default:
+			r.unknown("mark", mark.Type)
+		}
+	}
+	return txt
+}
+
+// mdCode rend s en code en ligne, délimité par plus de backticks qu'il n'en
+// contient.
+func mdCode(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	pad := ""
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		pad = " "
+	}
+	return fence + pad + s + pad + fence
+}
+
+// mdWrap entoure txt de open / close en laissant les espaces de bord à
+// l'extérieur (CommonMark refuse "** gras **").
+func mdWrap(txt, open, close string) string {
+	trimmed := strings.TrimSpace(txt)
+	if trimmed == "" {
+		return txt
+	}
+	lead := txt[:strings.Index(txt, trimmed)]
+	trail := txt[len(lead)+len(trimmed):]
+	return lead + open + trimmed + close + trail
+}
+
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+// EscapeMarkdown échappe les caractères qui ont un sens en Markdown.
+func EscapeMarkdown(s string) string {
+	return mdEscaper.Replace(s)
+}
+
+func mdEscape(s string) string {
+	return EscapeMarkdown(s)
+}
+
+// mdURL retourne u utilisable comme destination de lien.
+func mdURL(u string) string {
+	if strings.ContainsAny(u, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(u) + ">"
+	}
+	return u
+}
+
+// prefixLines préfixe la première ligne de s par first et les suivantes par
+// rest (les lignes vides ne reçoivent que la partie non blanche de rest).
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		p := rest
+		if i == 0 {
+			p = first
+		}
+		if l == "" {
+			p = strings.TrimRight(p, " ")
+		}
+		lines[i] = p + l
+	}
+	return strings.Join(lines, "\n")
+}
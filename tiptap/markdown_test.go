@@ -0,0 +1,139 @@
+package tiptap
+
+import "testing"
+
+func cell(typ string, content ...Node) Node {
+	return Node{Type: typ, Content: []Node{para(content...)}}
+}
+
+func row(cells ...Node) Node {
+	return Node{Type: "tableRow", Content: cells}
+}
+
+func item(content ...Node) Node {
+	return Node{Type: "listItem", Content: content}
+}
+
+func task(checked bool, content ...Node) Node {
+	return Node{Type: "taskItem", Attrs: map[string]interface{}{"checked": checked}, Content: content}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	bold := Mark{Type: "bold"}
+	code := Mark{Type: "code"}
+	for _, tt := range []struct {
+		name  string
+		nodes []Node
+		want  string
+	}{
+		{
+			name:  "special characters are escaped",
+			nodes: []Node{para(text(`# not a *title* [x](y) <b> _u_ \ ` + "`c`"))},
+			want:  `\# not a \*title\* \[x\](y) \<b\> \_u\_ \\ ` + "\\`c\\`\n",
+		},
+		{
+			name:  "marks keep edge spaces outside",
+			nodes: []Node{para(text("a "), text(" bold ", bold), text("x", Mark{Type: "underline"}))},
+			want:  "a  **bold** <u>x</u>\n",
+		},
+		{
+			name:  "inline code with backticks",
+			nodes: []Node{para(text("a`b", code, bold), text(" "), text("`x", code))},
+			want:  "**``a`b``** `` `x ``\n",
+		},
+		{
+			name:  "links keep only http(s)",
+			nodes: []Node{para(text("ok", link("https://e.com/a b")), text(" "), text("no", link("javascript:alert(1)")))},
+			want:  "[ok](<https://e.com/a b>) no\n",
+		},
+		{
+			name: "table with pipes and escapes",
+			nodes: []Node{{Type: "table", Content: []Node{
+				row(cell("tableHeader", text("a|b")), cell("tableHeader", text("*h*"))),
+				row(cell("tableCell", text(`x\|y`)), cell("tableCell", text("p|q", code))),
+				row(cell("tableCell", text("short"))),
+			}}},
+			want: "| a\\|b | \\*h\\* |\n" +
+				"| --- | --- |\n" +
+				"| x\\\\\\|y | `p\\|q` |\n" +
+				"| short |  |\n",
+		},
+		{
+			name: "table cell with several paragraphs stays on one line",
+			nodes: []Node{{Type: "table", Content: []Node{
+				row(Node{Type: "tableHeader", Content: []Node{para(text("one")), para(text("two"))}}),
+			}}},
+			want: "| one<br>two |\n| --- |\n",
+		},
+		{
+			name: "task list",
+			nodes: []Node{{Type: "taskList", Content: []Node{
+				task(true, para(text("done"))),
+				task(false, para(text("todo [later]"))),
+			}}},
+			want: "- [x] done\n- [ ] todo \\[later\\]\n",
+		},
+		{
+			name: "nested lists",
+			nodes: []Node{{Type: "bulletList", Content: []Node{
+				item(para(text("a")), Node{Type: "orderedList", Attrs: map[string]interface{}{"start": 3.0}, Content: []Node{
+					item(para(text("b"))),
+					item(para(text("c")), Node{Type: "bulletList", Content: []Node{item(para(text("d")))}}),
+				}}),
+				item(para(text("e"))),
+			}}},
+			want: "- a\n" +
+				"\n" +
+				"  3. b\n" +
+				"  4. c\n" +
+				"\n" +
+				"     - d\n" +
+				"- e\n",
+		},
+		{
+			name: "list item with two paragraphs",
+			nodes: []Node{{Type: "orderedList", Content: []Node{
+				item(para(text("first")), para(text("second"))),
+			}}},
+			want: "1. first\n\n   second\n",
+		},
+		{
+			name:  "blockquote",
+			nodes: []Node{{Type: "blockquote", Content: []Node{para(text("a")), para(text("b"))}}},
+			want:  "> a\n>\n> b\n",
+		},
+		{
+			name:  "code block fence longer than its content",
+			nodes: []Node{{Type: "codeBlock", Attrs: map[string]interface{}{"language": "md"}, Content: []Node{text("```go\nx\n```")}}},
+			want:  "````md\n```go\nx\n```\n````\n",
+		},
+		{
+			name:  "heading and hard break",
+			nodes: []Node{{Type: "heading", Attrs: map[string]interface{}{"level": 2.0}, Content: []Node{text("T")}}, para(text("a"), Node{Type: "hardBreak"}, text("b"))},
+			want:  "## T\n\na\\\nb\n",
+		},
+		{
+			name:  "unknown node",
+			nodes: []Node{{Type: "poll", Content: []Node{para(text("q"))}}},
+			want:  "> [contenu non pris en charge : poll]\n\nq\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.nodes); got != tt.want {
+				t.Errorf("RenderMarkdown =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescriptionMarkdown(t *testing.T) {
+	for desc, want := range map[string]string{
+		"":                               "",
+		"plain *text* | with [brackets]": `plain \*text\* | with \[brackets\]`,
+		`[v2][{"type":"paragraph","content":[{"type":"text","text":"hi"}]}]`: "hi",
+	} {
+		if got := DescriptionMarkdown(desc); got != want {
+			t.Errorf("DescriptionMarkdown(%q) = %q, want %q", desc, got, want)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+// Package tiptap convertit les descriptions Tiptap de Skool en HTML et en
+// extrait les liens.
+package tiptap
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Types
+// -----------------------------------------------------------------------------
+
+// Mark est un formatage appliqué à un nœud texte (bold, italic, link...).
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// Node est un nœud du document Tiptap.
+type Node struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text,omitempty"`
+	Marks   []Mark                 `json:"marks,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []Node                 `json:"content,omitempty"`
+}
+
+// -----------------------------------------------------------------------------
+// Parse => desc Skool ("[v2][{...}]") => []Node
+// -----------------------------------------------------------------------------
+
+// Parse extrait les nœuds Tiptap d'une description Skool. ok vaut false si
+// desc ne contient pas de JSON Tiptap lisible.
+func Parse(desc string) (nodes []Node, ok bool) {
+	idx := strings.Index(desc, "[{")
+	if idx < 0 {
+		return nil, false
+	}
+	u := html.UnescapeString(desc[idx:])
+
+	if err := json.Unmarshal([]byte(u), &nodes); err == nil {
+		return nodes, true
+	}
+	var root struct {
+		Content []Node `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(u), &root); err == nil && len(root.Content) > 0 {
+		return root.Content, true
+	}
+	return nil, false
+}
+
+// -----------------------------------------------------------------------------
+// Tiptap -> HTML natif lisible (p, h1, ul, li, a, strong, etc.)
+// -----------------------------------------------------------------------------
+
+// ImageSrcFunc réécrit au rendu le src d'une image (ex. vers une copie
+// locale). nil garde le src d'origine.
+type ImageSrcFunc func(src string) string
+
+// Renderer rend des nœuds Tiptap en HTML. Sa valeur zéro est utilisable.
+type Renderer struct {
+	// ImageSrc réécrit le src des images ; nil garde l'original.
+	ImageSrc ImageSrcFunc
+	// Unknown est appelé pour chaque nœud ou mark non pris en charge (kind
+	// vaut "node" ou "mark"), par exemple pour une trace de debug. Un nœud
+	// inconnu est rendu comme un encadré visible, suivi de son contenu.
+	Unknown func(kind, typ string)
+	// Unsupported est le texte de cet encadré ; "contenu non pris en
+	// charge" si vide.
+	Unsupported string
+	// XHTML produit du XHTML bien formé (éléments vides fermés, attributs
+	// booléens avec valeur), par exemple pour un EPUB.
+	XHTML bool
+}
+
+// DescriptionHTML convertit une description Skool en HTML. Le texte brut (ou
+// un JSON illisible) est rendu échappé dans un <p>.
+func DescriptionHTML(desc string) string {
+	return Renderer{}.DescriptionHTML(desc)
+}
+
+// RenderHTML rend une suite de nœuds en HTML.
+func RenderHTML(nodes []Node) string {
+	return Renderer{}.RenderHTML(nodes)
+}
+
+// DescriptionHTML est la fonction DescriptionHTML avec les options de r.
+func (r Renderer) DescriptionHTML(desc string) string {
+	if desc == "" {
+		return ""
+	}
+	desc = strings.ReplaceAll(desc, "[v2]", "")
+	if nodes, ok := Parse(desc); ok {
+		return strings.TrimSpace(r.RenderHTML(nodes))
+	}
+	return "<p>" + html.EscapeString(strings.TrimSpace(desc)) + "</p>"
+}
+
+// RenderHTML est la fonction RenderHTML avec les options de r.
+func (r Renderer) RenderHTML(nodes []Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		sb.WriteString(r.renderNode(node))
+	}
+	return sb.String()
+}
+
+// end ferme la balise ouvrante d'un élément vide : ">" ou "/>" en XHTML.
+func (r Renderer) end() string {
+	if r.XHTML {
+		return "/>"
+	}
+	return ">"
+}
+
+// boolAttr retourne l'attribut booléen name : " name", ou " name="name"" en
+// XHTML.
+func (r Renderer) boolAttr(name string) string {
+	if r.XHTML {
+		return " " + name + `="` + name + `"`
+	}
+	return " " + name
+}
+
+// unsupported retourne le texte de l'encadré d'un nœud inconnu.
+func (r Renderer) unsupported() string {
+	if r.Unsupported == "" {
+		return "contenu non pris en charge"
+	}
+	return r.Unsupported
+}
+
+func (r Renderer) unknown(kind, typ string) {
+	if r.Unknown != nil {
+		r.Unknown(kind, typ)
+	}
+}
+
+func (r Renderer) renderNode(node Node) string {
+	switch node.Type {
+	case "doc":
+		return r.RenderHTML(node.Content)
+	case "heading":
+		level := 1
+		if l, ok := node.Attrs["level"].(float64); ok && l >= 1 && l <= 6 {
+			level = int(l)
+		}
+		headingTag := fmt.Sprintf("h%d", level)
+		text := strings.TrimSpace(r.RenderHTML(node.Content))
+		return fmt.Sprintf("<%s>%s</%s>\n", headingTag, text, headingTag)
+	case "paragraph":
+		txt := strings.TrimSpace(r.RenderHTML(node.Content))
+		if txt == "" {
+			return ""
+		}
+		return "<p>" + txt + "</p>\n"
+	case "bulletList":
+		return "<ul>\n" + r.RenderHTML(node.Content) + "</ul>\n"
+	case "orderedList":
+		start := ""
+		if s, ok := node.Attrs["start"].(float64); ok && s != 1 {
+			start = fmt.Sprintf(` start="%d"`, int(s))
+		}
+		return "<ol" + start + ">\n" + r.RenderHTML(node.Content) + "</ol>\n"
+	case "listItem":
+		return "<li>" + strings.TrimSpace(r.RenderHTML(node.Content)) + "</li>\n"
+	case "taskList":
+		return `<ul class="task-list">` + "\n" + r.RenderHTML(node.Content) + "</ul>\n"
+	case "taskItem":
+		attrs := r.boolAttr("disabled")
+		if c, _ := node.Attrs["checked"].(bool); c {
+			attrs += r.boolAttr("checked")
+		}
+		return `<li class="task-item"><input type="checkbox"` + attrs + r.end() + " " +
+			strings.TrimSpace(r.RenderHTML(node.Content)) + "</li>\n"
+	case "text":
+		return r.renderText(node)
+	case "hardBreak":
+		return "<br" + r.end() + "\n"
+	case "horizontalRule":
+		return "<hr" + r.end() + "\n"
+	case "blockquote":
+		content := strings.TrimSpace(r.RenderHTML(node.Content))
+		return "<blockquote>" + content + "</blockquote>\n"
+	case "codeBlock":
+		class := ""
+		if lang, _ := node.Attrs["language"].(string); lang != "" {
+			class = ` class="language-` + html.EscapeString(lang) + `"`
+		}
+		return "<pre><code" + class + ">" + html.EscapeString(plainText(node.Content)) + "</code></pre>\n"
+	case "table":
+		return "<table>\n" + r.RenderHTML(node.Content) + "</table>\n"
+	case "tableRow":
+		return "<tr>" + r.RenderHTML(node.Content) + "</tr>\n"
+	case "tableCell", "tableHeader":
+		tag := "td"
+		if node.Type == "tableHeader" {
+			tag = "th"
+		}
+		span := ""
+		for _, a := range []string{"colspan", "rowspan"} {
+			if n, ok := node.Attrs[a].(float64); ok && n > 1 {
+				span += fmt.Sprintf(` %s="%d"`, a, int(n))
+			}
+		}
+		return "<" + tag + span + ">" + strings.TrimSpace(r.RenderHTML(node.Content)) + "</" + tag + ">"
+	case "mention":
+		label := attrString(node.Attrs, "label", "name", "id")
+		return `<span class="mention">@` + html.EscapeString(label) + "</span>"
+	case "emoji":
+		if e := attrString(node.Attrs, "emoji"); e != "" {
+			return html.EscapeString(e)
+		}
+		if node.Text != "" {
+			return html.EscapeString(node.Text)
+		}
+		return html.EscapeString(":" + attrString(node.Attrs, "name") + ":")
+	case "image":
+		src, _ := node.Attrs["src"].(string)
+		if src == "" {
+			return ""
+		}
+		if r.ImageSrc != nil {
+			src = r.ImageSrc(src)
+		}
+		alt, _ := node.Attrs["alt"].(string)
+		out := `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `"`
+		if title, _ := node.Attrs["title"].(string); title != "" {
+			out += ` title="` + html.EscapeString(title) + `"`
+		}
+		return out + r.end() + "\n"
+	case "video":
+		src := webURL(attrString(node.Attrs, "src"))
+		if src == "" {
+			return ""
+		}
+		return `<video` + r.boolAttr("controls") + ` src="` + html.EscapeString(src) + `"></video>` + "\n"
+	case "youtube", "iframe", "embed", "loom", "vimeo":
+		src := webURL(attrString(node.Attrs, "src", "url", "href"))
+		if src == "" {
+			return ""
+		}
+		s := html.EscapeString(src)
+		return `<div class="embed"><iframe src="` + s + `"` + r.boolAttr("allowfullscreen") + `></iframe>` +
+			`<p><a href="` + s + `" target="_blank">` + s + "</a></p></div>\n"
+	}
+
+	r.unknown("node", node.Type)
+	return `<div class="tiptap-unknown">[` + html.EscapeString(r.unsupported()) + " : " + html.EscapeString(node.Type) + "]</div>\n" +
+		r.RenderHTML(node.Content)
+}
+
+// renderText rend un nœud texte et ses marks.
+func (r Renderer) renderText(node Node) string {
+	txt := html.EscapeString(node.Text)
+	for _, mark := range node.Marks {
+		switch mark.Type {
+		case "bold":
+			txt = "<strong>" + txt + "</strong>"
+		case "italic":
+			txt = "<em>" + txt + "</em>"
+		case "underline":
+			txt = "<u>" + txt + "</u>"
+		case "strike":
+			txt = "<s>" + txt + "</s>"
+		case "code":
+			txt = "<code>" + txt + "</code>"
+		case "subscript":
+			txt = "<sub>" + txt + "</sub>"
+		case "superscript":
+			txt = "<sup>" + txt + "</sup>"
+		case "highlight":
+			style := ""
+			if c := cssColor(mark.Attrs["color"]); c != "" {
+				style = ` style="background-color: ` + c + `"`
+			}
+			txt = "<mark" + style + ">" + txt + "</mark>"
+		case "textStyle":
+			if c := cssColor(mark.Attrs["color"]); c != "" {
+				txt = `<span style="color: ` + c + `">` + txt + "</span>"
+			}
+		case "link":
+			// Un lien javascript:, data:... est rendu comme du texte.
+			if href := webURL(attrString(mark.Attrs, "href")); href != "" {
+				txt = "<a href=\"" + html.EscapeString(href) + "\" target=\"_blank\">" + txt + "</a>"
+			}
+		default:
+			r.unknown("mark", mark.Type)
+		}
+	}
+	return txt
+}
+
+// webURL retourne u s'il s'agit d'une URL http ou https, "" sinon : les
+// schémas javascript:, data:... ne doivent pas atteindre le HTML exporté.
+func webURL(u string) string {
+	u = strings.TrimSpace(u)
+	p, err := url.Parse(u)
+	if err != nil || (p.Scheme != "http" && p.Scheme != "https") {
+		return ""
+	}
+	return u
+}
+
+// reCSSColor n'accepte que des couleurs CSS simples (#hex, nom, rgb(...)).
+var reCSSColor = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+|(rgb|rgba|hsl|hsla)\([0-9.,%\s]+\))$`)
+
+func cssColor(v interface{}) string {
+	c, _ := v.(string)
+	c = strings.TrimSpace(c)
+	if !reCSSColor.MatchString(c) {
+		return ""
+	}
+	return c
+}
+
+// attrString retourne le premier attribut chaîne non vide parmi keys.
+func attrString(attrs map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := attrs[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// plainText concatène le texte brut de nodes (sans marks).
+func plainText(nodes []Node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		if n.Type == "hardBreak" {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(n.Text)
+		sb.WriteString(plainText(n.Content))
+	}
+	return sb.String()
+}
+
+// -----------------------------------------------------------------------------
+// Liens => parse la version Node pour .Marks => link
+// -----------------------------------------------------------------------------
+
+// LoomVimeoLinks retourne les liens Loom/Vimeo présents dans une description
+// (liens du texte et vidéos intégrées).
+func LoomVimeoLinks(desc string) []string {
+	nodes, ok := Parse(desc)
+	if !ok {
+		return nil
+	}
+	return FilterLoomVimeo(uniqueStrings(append(Links(nodes), Embeds(nodes)...)))
+}
+
+// Links retourne tous les href des marks "link" de nodes, dans l'ordre.
+func Links(nodes []Node) []string {
+	var out []string
+	traverseNodesForLinks(nodes, &out)
+	return out
+}
+
+func traverseNodesForLinks(nodes []Node, out *[]string) {
+	for _, n := range nodes {
+		for _, mk := range n.Marks {
+			if mk.Type == "link" {
+				if href, ok := mk.Attrs["href"].(string); ok {
+					*out = append(*out, href)
+				}
+			}
+		}
+		if len(n.Content) > 0 {
+			traverseNodesForLinks(n.Content, out)
+		}
+	}
+}
+
+// embedTypes sont les nœuds de vidéo / iframe intégrés.
+var embedTypes = map[string]bool{"video": true, "youtube": true, "iframe": true, "embed": true, "loom": true, "vimeo": true}
+
+// Embeds retourne les src des vidéos et iframes intégrées de nodes, dans
+// l'ordre.
+func Embeds(nodes []Node) []string {
+	var out []string
+	for _, n := range nodes {
+		if embedTypes[n.Type] {
+			if src := attrString(n.Attrs, "src", "url", "href"); src != "" {
+				out = append(out, src)
+			}
+		}
+		out = append(out, Embeds(n.Content)...)
+	}
+	return out
+}
+
+// Images retourne les src des nœuds image de nodes, dans l'ordre.
+func Images(nodes []Node) []string {
+	var out []string
+	for _, n := range nodes {
+		if n.Type == "image" {
+			if src, ok := n.Attrs["src"].(string); ok && src != "" {
+				out = append(out, src)
+			}
+		}
+		out = append(out, Images(n.Content)...)
+	}
+	return out
+}
+
+// ImageURLs retourne les src distincts des images d'une description.
+func ImageURLs(desc string) []string {
+	nodes, ok := Parse(desc)
+	if !ok {
+		return nil
+	}
+	return uniqueStrings(Images(nodes))
+}
+
+// FilterLoomVimeo ne garde que les liens loom.com et vimeo.com.
+func FilterLoomVimeo(links []string) []string {
+	var out []string
+	for _, s := range links {
+		ll := strings.ToLower(s)
+		if strings.Contains(ll, "loom.com") || strings.Contains(ll, "vimeo.com") {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func uniqueStrings(arr []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range arr {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
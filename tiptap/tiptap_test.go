@@ -0,0 +1,192 @@
+package tiptap
+
+import (
+	"strings"
+	"testing"
+)
+
+// text retourne un nœud texte avec les marks données.
+func text(s string, marks ...Mark) Node {
+	return Node{Type: "text", Text: s, Marks: marks}
+}
+
+func link(href string) Mark {
+	return Mark{Type: "link", Attrs: map[string]interface{}{"href": href}}
+}
+
+func para(content ...Node) Node {
+	return Node{Type: "paragraph", Content: content}
+}
+
+func TestRenderHTMLSanitizes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		node Node
+		want string
+	}{
+		{
+			name: "text is escaped",
+			node: para(text(`<script>alert("x")</script> & co`)),
+			want: "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; co</p>\n",
+		},
+		{
+			name: "https link",
+			node: para(text("site", link("https://example.com/?a=1&b=2"))),
+			want: `<p><a href="https://example.com/?a=1&amp;b=2" target="_blank">site</a></p>` + "\n",
+		},
+		{
+			name: "javascript link becomes text",
+			node: para(text("click", link("javascript:alert(1)"))),
+			want: "<p>click</p>\n",
+		},
+		{
+			name: "uppercase and spaced javascript link",
+			node: para(text("click", link("  JavaScript:alert(1)"))),
+			want: "<p>click</p>\n",
+		},
+		{
+			name: "data link becomes text",
+			node: para(text("x", link("data:text/html;base64,PHNjcmlwdD4="))),
+			want: "<p>x</p>\n",
+		},
+		{
+			name: "relative link becomes text",
+			node: para(text("x", link("/local"))),
+			want: "<p>x</p>\n",
+		},
+		{
+			name: "href attribute breakout is escaped",
+			node: para(text("x", link(`https://e.com/" onmouseover="alert(1)`))),
+			want: `<p><a href="https://e.com/&#34; onmouseover=&#34;alert(1)" target="_blank">x</a></p>` + "\n",
+		},
+		{
+			name: "javascript iframe is dropped",
+			node: Node{Type: "iframe", Attrs: map[string]interface{}{"src": "javascript:alert(1)"}},
+			want: "",
+		},
+		{
+			name: "javascript video is dropped",
+			node: Node{Type: "video", Attrs: map[string]interface{}{"src": "javascript:alert(1)"}},
+			want: "",
+		},
+		{
+			name: "embed src is escaped",
+			node: Node{Type: "youtube", Attrs: map[string]interface{}{"src": `https://y.com/e?a=1&b="2"`}},
+			want: `<div class="embed"><iframe src="https://y.com/e?a=1&amp;b=&#34;2&#34;" allowfullscreen></iframe>` +
+				`<p><a href="https://y.com/e?a=1&amp;b=&#34;2&#34;" target="_blank">https://y.com/e?a=1&amp;b=&#34;2&#34;</a></p></div>` + "\n",
+		},
+		{
+			name: "image attributes are escaped",
+			node: Node{Type: "image", Attrs: map[string]interface{}{"src": `x.png" onerror="alert(1)`, "alt": "<b>"}},
+			want: `<img src="x.png&#34; onerror=&#34;alert(1)" alt="&lt;b&gt;">` + "\n",
+		},
+		{
+			name: "code block language is escaped",
+			node: Node{Type: "codeBlock", Attrs: map[string]interface{}{"language": `go"><script>`}, Content: []Node{text("<b>")}},
+			want: `<pre><code class="language-go&#34;&gt;&lt;script&gt;">&lt;b&gt;</code></pre>` + "\n",
+		},
+		{
+			name: "mention label is escaped",
+			node: Node{Type: "mention", Attrs: map[string]interface{}{"label": "<img>"}},
+			want: `<span class="mention">@&lt;img&gt;</span>`,
+		},
+		{
+			name: "valid color",
+			node: text("c", Mark{Type: "textStyle", Attrs: map[string]interface{}{"color": "#ff0000"}}),
+			want: `<span style="color: #ff0000">c</span>`,
+		},
+		{
+			name: "rgb highlight",
+			node: text("c", Mark{Type: "highlight", Attrs: map[string]interface{}{"color": "rgb(1, 2, 3)"}}),
+			want: `<mark style="background-color: rgb(1, 2, 3)">c</mark>`,
+		},
+		{
+			name: "CSS injection in color is dropped",
+			node: text("c", Mark{Type: "textStyle", Attrs: map[string]interface{}{"color": `red; background: url("javascript:x")`}}),
+			want: "c",
+		},
+		{
+			name: "attribute breakout in color is dropped",
+			node: text("c", Mark{Type: "highlight", Attrs: map[string]interface{}{"color": `red" onclick="x`}}),
+			want: "<mark>c</mark>",
+		},
+		{
+			name: "expression() in color is dropped",
+			node: text("c", Mark{Type: "textStyle", Attrs: map[string]interface{}{"color": "expression(alert(1))"}}),
+			want: "c",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderHTML([]Node{tt.node}); got != tt.want {
+				t.Errorf("RenderHTML =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderHTMLUnknown(t *testing.T) {
+	var seen []string
+	r := Renderer{Unknown: func(kind, typ string) { seen = append(seen, kind+":"+typ) }}
+	got := r.RenderHTML([]Node{
+		{Type: `<script>`, Content: []Node{para(text("inner", Mark{Type: "blink"}))}},
+	})
+	want := `<div class="tiptap-unknown">[contenu non pris en charge : &lt;script&gt;]</div>` + "\n<p>inner</p>\n"
+	if got != want {
+		t.Errorf("RenderHTML =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Join(seen, ",") != "node:<script>,mark:blink" {
+		t.Errorf("Unknown calls = %q", seen)
+	}
+
+	r = Renderer{Unsupported: `unsupported <content>`}
+	if got := r.RenderHTML([]Node{{Type: "poll"}}); got != `<div class="tiptap-unknown">[unsupported &lt;content&gt; : poll]</div>`+"\n" {
+		t.Errorf("Unsupported label: %s", got)
+	}
+}
+
+func TestDescriptionHTML(t *testing.T) {
+	for desc, want := range map[string]string{
+		"":                       "",
+		"plain <b>text</b> & co": "<p>plain &lt;b&gt;text&lt;/b&gt; &amp; co</p>",
+		"[v2][{broken json":      "<p>[{broken json</p>",
+		`[v2][{"type":"paragraph","content":[{"type":"text","text":"hi"}]}]`: "<p>hi</p>",
+		// Parse lit le JSON échappé en entités, mais le texte reste échappé.
+		`[{&quot;type&quot;:&quot;paragraph&quot;,&quot;content&quot;:[{&quot;type&quot;:&quot;text&quot;,&quot;text&quot;:&quot;&lt;i&gt;&quot;}]}]`: "<p>&lt;i&gt;</p>",
+		// Un document valide sans contenu rendu ne retombe pas sur le texte brut.
+		`[{"type":"paragraph"}]`: "",
+	} {
+		if got := DescriptionHTML(desc); got != want {
+			t.Errorf("DescriptionHTML(%q) = %q, want %q", desc, got, want)
+		}
+	}
+}
+
+func TestWebURL(t *testing.T) {
+	for u, want := range map[string]string{
+		"https://example.com":    "https://example.com",
+		" http://example.com/a ": "http://example.com/a",
+		"HTTPS://EXAMPLE.COM":    "HTTPS://EXAMPLE.COM",
+		"javascript:alert(1)":    "",
+		"vbscript:msgbox":        "",
+		"data:text/html,x":       "",
+		"//example.com":          "",
+		"mailto:a@b.c":           "",
+		"":                       "",
+	} {
+		if got := webURL(u); got != want {
+			t.Errorf("webURL(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
+
+func TestXHTML(t *testing.T) {
+	r := Renderer{XHTML: true}
+	got := r.RenderHTML([]Node{
+		{Type: "taskItem", Attrs: map[string]interface{}{"checked": true}, Content: []Node{para(text("done"))}},
+		{Type: "horizontalRule"},
+	})
+	want := `<li class="task-item"><input type="checkbox" disabled="disabled" checked="checked"/> <p>done</p></li>` + "\n<hr/>\n"
+	if got != want {
+		t.Errorf("RenderHTML =\n%s\nwant\n%s", got, want)
+	}
+}
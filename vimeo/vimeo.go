@@ -0,0 +1,156 @@
+// Package vimeo normalise les différentes formes d'URL Vimeo.
+package vimeo
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	reVimeoNum   = regexp.MustCompile(`(?i)vimeo\.com/(?:video/)?(\d+)`)
+	reVimeoIDRaw = regexp.MustCompile(`vimeo\.com/(?:video/)?(\d+)(?:/([a-zA-Z0-9]+))?`)
+	reAlnum      = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+)
+
+// IsVimeo indique si link pointe vers vimeo.com.
+func IsVimeo(link string) bool {
+	return strings.Contains(strings.ToLower(link), "vimeo.com")
+}
+
+// IsShareLink indique si link est un lien de partage /video/share?h=...,
+// qui doit être conservé tel quel.
+func IsShareLink(link string) bool {
+	return strings.Contains(link, "vimeo.com/video/share?h=")
+}
+
+// parseIDHash extrait l'ID et le hash (privé) d'une URL Vimeo.
+func parseIDHash(u *url.URL) (id, hash string) {
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	hash = u.Query().Get("h")
+	if len(segs) > 0 {
+		if segs[0] == "video" {
+			if len(segs) > 1 {
+				// Special case: if path is /video/share, use hash as ID
+				if segs[1] == "share" {
+					id = hash // Use hash as the actual video ID
+				} else {
+					id = segs[1]
+				}
+			}
+			if len(segs) > 2 && segs[1] != "share" {
+				hash = segs[2]
+			}
+		} else {
+			id = segs[0]
+			if len(segs) > 1 {
+				hash = segs[1]
+			}
+		}
+	}
+	return id, hash
+}
+
+// -----------------------------------------------------------------------------
+// Essayer toutes les variantes d'URL Vimeo
+// -----------------------------------------------------------------------------
+
+// AllURLs retourne les variantes d'URL à essayer pour télécharger link, de la
+// plus à la moins précise. link lui-même est toujours inclus en dernier.
+func AllURLs(link string) []string {
+	id := ""
+	hash := ""
+
+	if u, err := url.Parse(link); err == nil {
+		id, hash = parseIDHash(u)
+		// Override hash from query parameter if present
+		if h := u.Query().Get("h"); h != "" {
+			hash = h
+		}
+	} else {
+		m := reVimeoIDRaw.FindStringSubmatch(link)
+		if len(m) > 1 {
+			id = m[1]
+		}
+		if len(m) > 2 {
+			hash = m[2]
+		}
+	}
+
+	// Fallback: if we still don't have a valid ID, try to extract from hash if it looks like an ID
+	if id == "" || id == "share" {
+		// Check if hash looks like a valid ID (alphanumeric)
+		if hash != "" && reAlnum.MatchString(hash) {
+			id = hash
+		}
+	}
+
+	var urls []string
+	if id != "" && id != "share" {
+		if hash != "" && hash != id {
+			urls = append(urls, fmt.Sprintf("https://player.vimeo.com/video/%s?h=%s", id, hash))
+		}
+		urls = append(urls, fmt.Sprintf("https://player.vimeo.com/video/%s", id))
+		if hash != "" && hash != id {
+			urls = append(urls, fmt.Sprintf("https://vimeo.com/%s/%s", id, hash))
+		}
+		urls = append(urls, fmt.Sprintf("https://vimeo.com/%s", id))
+	}
+	if !contains(urls, link) {
+		urls = append(urls, link)
+	}
+	return urls
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+// ToPlayer => vimeo.com/\d+ => player
+// -----------------------------------------------------------------------------
+
+// ToPlayer réécrit link en URL player.vimeo.com. link est retourné inchangé
+// s'il est déjà une URL player ou si aucun ID n'est trouvé.
+func ToPlayer(link string) string {
+	if strings.Contains(link, "player.vimeo.com") {
+		return link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	id, hash := parseIDHash(u)
+
+	if id == "" {
+		m := reVimeoNum.FindStringSubmatch(link)
+		if len(m) > 1 {
+			id = m[1]
+		}
+	}
+
+	// Fallback: if we still don't have a valid ID, try to extract from hash if it looks like an ID
+	if id == "" || id == "share" {
+		// Check if hash looks like a valid ID (alphanumeric)
+		if hash != "" && reAlnum.MatchString(hash) {
+			id = hash
+		}
+	}
+
+	if id == "" || id == "share" {
+		return link
+	}
+
+	if hash != "" && hash != id {
+		return fmt.Sprintf("https://player.vimeo.com/video/%s?h=%s", id, hash)
+	}
+	return fmt.Sprintf("https://player.vimeo.com/video/%s", id)
+}